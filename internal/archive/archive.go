@@ -0,0 +1,382 @@
+// Package archive reads tar, gzip-compressed tar and zip archives as a
+// read-only fs.FS without extracting them to disk.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rjwalters/ghloc/internal/memfs"
+)
+
+// ErrTooLarge is returned when reading a member larger than the size cap.
+// Such members stay listed, with their real size, so callers can report them.
+var ErrTooLarge = errors.New("archive member exceeds size cap")
+
+// FS is the contents of an archive. A single top-level directory wrapping
+// every member, as in GitHub source tarballs, becomes the root.
+//
+// Tar archives cannot be read at random, so Open only indexes their headers
+// and Walk streams the contents. Reading a tar member outside Walk scans the
+// archive up to it, which is fine for a few files but slow for many.
+type FS struct {
+	*memfs.FS
+	file        *os.File
+	zip         bool
+	maxFileSize int64
+	files       []regularFile // in archive order
+
+	// The tar member Walk is visiting, served to reads of its names
+	current     int
+	currentData []byte
+}
+
+// regularFile is a regular file or hard link in the tree. source is the
+// index of the tar member holding its contents.
+type regularFile struct {
+	name   string
+	size   int64
+	source int
+}
+
+// member is an archive entry collected before the tree is built.
+type member struct {
+	name    string
+	mode    fs.FileMode
+	size    int64
+	modTime time.Time
+	load    memfs.LoadFunc
+	source  int // tar member holding the contents, or -1
+}
+
+// metadataFiles are kept in memory while indexing a tar archive, since they
+// are read before counting starts. They are small.
+var metadataFiles = []string{".gitattributes", ".gitmodules"}
+
+// Open indexes the archive at path, detecting its format from its contents.
+// Tar archives are read once without holding file contents in memory; zip
+// members are read on demand. Members larger than maxFileSize bytes are
+// listed but not read; a maxFileSize of zero or less disables the cap.
+// The archive stays open until Close.
+func Open(path string, maxFileSize int64) (*FS, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open archive: %w", err)
+	}
+	a := &FS{file: f, maxFileSize: maxFileSize, current: -1}
+	if err := a.index(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("read archive %s: %w", path, err)
+	}
+	return a, nil
+}
+
+// index reads the archive's member list and builds the tree.
+func (a *FS) index() error {
+	r, isZip, err := a.open()
+	if err != nil {
+		return err
+	}
+	var members []member
+	if isZip {
+		a.zip = true
+		zr, err := zip.NewReader(a.file, mustSize(a.file))
+		if err != nil {
+			return err
+		}
+		members = zipMembers(zr, a.maxFileSize)
+	} else if members, err = a.tarMembers(tar.NewReader(r)); err != nil {
+		return err
+	}
+	a.FS, a.files, err = buildTree(members)
+	return err
+}
+
+// open rewinds the archive file and returns a reader over its tar stream,
+// decompressing gzip, or reports that it is a zip archive.
+func (a *FS) open() (r io.Reader, isZip bool, err error) {
+	if _, err := a.file.Seek(0, io.SeekStart); err != nil {
+		return nil, false, err
+	}
+	br := bufio.NewReader(a.file)
+	magic, _ := br.Peek(4)
+	switch {
+	case bytes.HasPrefix(magic, []byte("PK\x03\x04")), bytes.HasPrefix(magic, []byte("PK\x05\x06")):
+		return nil, true, nil
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(br)
+		return gz, false, err
+	default:
+		return br, false, nil
+	}
+}
+
+func mustSize(f *os.File) int64 {
+	info, err := f.Stat()
+	if err != nil {
+		return 0 // zip.NewReader then reports the archive as invalid
+	}
+	return info.Size()
+}
+
+// Close releases the archive file.
+func (a *FS) Close() error {
+	return a.file.Close()
+}
+
+// Walk calls fn with the name and size of every regular file in the tree,
+// in the order the archive stores them, so that a tar archive is read only
+// once. While fn runs, reading name returns its contents from the stream
+// without a further scan. Hard-linked names are visited together. Walk
+// stops at the first error fn returns.
+func (a *FS) Walk(fn func(name string, size int64) error) error {
+	if a.zip {
+		for _, f := range a.files {
+			if err := fn(f.name, f.size); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	names := make(map[int][]regularFile) // by source member
+	for _, f := range a.files {
+		names[f.source] = append(names[f.source], f)
+	}
+	defer func() { a.current, a.currentData = -1, nil }()
+	return a.scanTar(func(i int, hdr *tar.Header, tr *tar.Reader) (bool, error) {
+		files, ok := names[i]
+		if !ok {
+			return false, nil
+		}
+		a.current, a.currentData = i, nil
+		if a.maxFileSize <= 0 || hdr.Size <= a.maxFileSize {
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return false, err
+			}
+			a.currentData = data
+		}
+		for _, f := range files {
+			if err := fn(f.name, f.size); err != nil {
+				return false, err
+			}
+		}
+		a.current, a.currentData = -1, nil
+		return false, nil
+	})
+}
+
+// scanTar calls fn for each tar header, with its index among all headers,
+// until fn reports that it is done.
+func (a *FS) scanTar(fn func(i int, hdr *tar.Header, tr *tar.Reader) (done bool, err error)) error {
+	r, _, err := a.open()
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(r)
+	for i := 0; ; i++ {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if done, err := fn(i, hdr, tr); done || err != nil {
+			return err
+		}
+	}
+}
+
+// readTarMember returns the contents of the tar member at index i, from
+// the stream if Walk is visiting it and by scanning the archive otherwise.
+func (a *FS) readTarMember(i int) ([]byte, error) {
+	if i == a.current {
+		return bytes.Clone(a.currentData), nil
+	}
+	var data []byte
+	found := false
+	err := a.scanTar(func(k int, _ *tar.Header, tr *tar.Reader) (bool, error) {
+		if k != i {
+			return false, nil
+		}
+		var err error
+		data, err = io.ReadAll(tr)
+		found = true
+		return true, err
+	})
+	if err == nil && !found {
+		err = io.ErrUnexpectedEOF // the archive changed since it was indexed
+	}
+	return data, err
+}
+
+// tarMembers lists a tar archive, skipping over file contents.
+func (a *FS) tarMembers(tr *tar.Reader) ([]member, error) {
+	var members []member
+	sources := make(map[string]int) // member holding each name's contents, for hard links
+	for i := 0; ; i++ {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return members, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		name, ok := cleanName(hdr.Name)
+		if !ok {
+			continue
+		}
+
+		m := member{name: name, size: hdr.Size, modTime: hdr.ModTime, source: -1}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			m.mode = fs.ModeDir
+		case tar.TypeSymlink:
+			m.mode = fs.ModeSymlink | 0777
+			m.load = memfs.Bytes([]byte(hdr.Linkname))
+		case tar.TypeLink:
+			target, _ := cleanName(hdr.Linkname)
+			source, ok := sources[target]
+			if !ok {
+				continue // link to a member we did not keep
+			}
+			m.mode, m.source = 0644, source
+		case tar.TypeReg:
+			m.mode, m.source = fs.FileMode(hdr.Mode).Perm(), i
+		default:
+			continue // devices, FIFOs and other special files
+		}
+
+		if m.source >= 0 {
+			sources[name] = m.source
+			switch {
+			case a.maxFileSize > 0 && m.size > a.maxFileSize:
+				m.load = tooLarge
+			case m.source == i && slices.Contains(metadataFiles, path.Base(name)):
+				data, err := io.ReadAll(tr)
+				if err != nil {
+					return nil, err
+				}
+				m.load = memfs.Bytes(data)
+			default:
+				source := m.source
+				m.load = func() ([]byte, error) { return a.readTarMember(source) }
+			}
+		}
+		members = append(members, m) // tr.Next skips unread contents
+	}
+}
+
+// zipMembers lists a zip archive, reading members lazily.
+func zipMembers(zr *zip.Reader, maxFileSize int64) []member {
+	var members []member
+	for _, f := range zr.File {
+		name, ok := cleanName(f.Name)
+		if !ok {
+			continue
+		}
+		info := f.FileInfo()
+		m := member{name: name, mode: info.Mode(), size: info.Size(), modTime: info.ModTime(), source: -1}
+		switch {
+		case info.IsDir():
+		case maxFileSize > 0 && info.Size() > maxFileSize:
+			m.load = tooLarge
+		default:
+			m.load = func() ([]byte, error) {
+				rc, err := f.Open()
+				if err != nil {
+					return nil, err
+				}
+				defer rc.Close()
+				return io.ReadAll(rc)
+			}
+		}
+		members = append(members, m)
+	}
+	return members
+}
+
+func tooLarge() ([]byte, error) {
+	return nil, ErrTooLarge
+}
+
+// cleanName converts an archive member name to an fs.FS path, rejecting
+// names that would escape the root.
+func cleanName(name string) (string, bool) {
+	name = path.Clean(strings.TrimLeft(strings.ReplaceAll(name, `\`, "/"), "/"))
+	if name == "." || !fs.ValidPath(name) {
+		return "", false
+	}
+	return name, true
+}
+
+// buildTree adds members to a tree, dropping a single top-level directory
+// that contains everything, and returns its regular files in archive order.
+// When a name repeats, as in appended tar archives, the last member wins.
+func buildTree(members []member) (*memfs.FS, []regularFile, error) {
+	last := make(map[string]int, len(members))
+	for i, m := range members {
+		last[m.name] = i
+	}
+
+	prefix := commonRoot(members)
+	tree := memfs.New()
+	var files []regularFile
+	for i, m := range members {
+		if last[m.name] != i {
+			continue
+		}
+		name, ok := strings.CutPrefix(m.name, prefix)
+		if !ok || name == "" {
+			continue // the wrapping directory itself
+		}
+		var err error
+		if m.mode.IsDir() {
+			err = tree.AddDir(name)
+		} else {
+			err = tree.Add(name, m.mode, m.size, m.modTime, m.load)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if m.mode.IsRegular() {
+			files = append(files, regularFile{name: name, size: m.size, source: m.source})
+		}
+	}
+	return tree, files, nil
+}
+
+// commonRoot returns "dir/" if every member lies inside the top-level
+// directory dir, and "" otherwise.
+func commonRoot(members []member) string {
+	var root string
+	for _, m := range members {
+		first, _, nested := strings.Cut(m.name, "/")
+		if !nested && !m.mode.IsDir() {
+			return "" // a file at the top level
+		}
+		if root == "" {
+			root = first
+		} else if first != root {
+			return ""
+		}
+	}
+	if root == "" {
+		return ""
+	}
+	return root + "/"
+}
@@ -0,0 +1,198 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+// testFile is a member of the archives built by the tests.
+type testFile struct {
+	name, data, link string
+}
+
+var testFiles = []testFile{
+	{name: "proj-1.0/"},
+	{name: "proj-1.0/main.go", data: "package main\n\nfunc main() {}\n"},
+	{name: "proj-1.0/src/lib.go", data: "package src\n"},
+	{name: "proj-1.0/big.txt", data: string(bytes.Repeat([]byte("x\n"), 100))},
+	{name: "proj-1.0/alias.go", link: "main.go"},
+	{name: "../evil.go", data: "package evil\n"},
+}
+
+func writeTar(t *testing.T, path string, compress bool) {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, f := range testFiles {
+		hdr := &tar.Header{Name: f.name, Mode: 0644, Size: int64(len(f.data)), Typeflag: tar.TypeReg}
+		switch {
+		case f.link != "":
+			hdr.Typeflag, hdr.Linkname, hdr.Size = tar.TypeSymlink, f.link, 0
+		case f.name[len(f.name)-1] == '/':
+			hdr.Typeflag, hdr.Mode = tar.TypeDir, 0755
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(f.data))
+	}
+	tw.Close()
+
+	data := buf.Bytes()
+	if compress {
+		var gz bytes.Buffer
+		zw := gzip.NewWriter(&gz)
+		zw.Write(data)
+		zw.Close()
+		data = gz.Bytes()
+	}
+	os.WriteFile(path, data, 0644)
+}
+
+func writeZip(t *testing.T, path string) {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range testFiles {
+		hdr := &zip.FileHeader{Name: f.name, Method: zip.Deflate}
+		switch {
+		case f.link != "":
+			hdr.SetMode(fs.ModeSymlink | 0777)
+			f.data = f.link
+		case f.name[len(f.name)-1] == '/':
+			hdr.SetMode(fs.ModeDir | 0755)
+		default:
+			hdr.SetMode(0644)
+		}
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(f.data))
+	}
+	zw.Close()
+	os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	archives := map[string]func(path string){
+		"proj.tar":    func(p string) { writeTar(t, p, false) },
+		"proj.tar.gz": func(p string) { writeTar(t, p, true) },
+		"proj.zip":    func(p string) { writeZip(t, p) },
+	}
+
+	for name, write := range archives {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			write(path)
+
+			a, err := Open(path, 100)
+			if err != nil {
+				t.Fatalf("Open() error: %v", err)
+			}
+			defer a.Close()
+
+			// The wrapping proj-1.0/ directory becomes the root
+			if data, err := fs.ReadFile(a, "main.go"); err != nil || string(data) != "package main\n\nfunc main() {}\n" {
+				t.Errorf("ReadFile(main.go) = %q, %v", data, err)
+			}
+			if data, err := fs.ReadFile(a, "alias.go"); err != nil || string(data) != "package main\n\nfunc main() {}\n" {
+				t.Errorf("ReadFile through symlink = %q, %v", data, err)
+			}
+			if _, err := fs.Stat(a, "evil.go"); err == nil {
+				t.Error("member outside the archive root should be dropped")
+			}
+
+			// Oversized members are listed but not read
+			info, err := fs.Stat(a, "big.txt")
+			if err != nil || info.Size() != 200 {
+				t.Errorf("Stat(big.txt) = %v, %v", info, err)
+			}
+			if _, err := fs.ReadFile(a, "big.txt"); !errors.Is(err, ErrTooLarge) {
+				t.Errorf("ReadFile(big.txt) error = %v, want ErrTooLarge", err)
+			}
+		})
+	}
+}
+
+func TestOpen_NoCap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "proj.tgz")
+	writeTar(t, path, true)
+
+	a, err := Open(path, 0)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	defer a.Close()
+	if data, err := fs.ReadFile(a, "big.txt"); err != nil || len(data) != 200 {
+		t.Errorf("ReadFile(big.txt) = %d bytes, %v", len(data), err)
+	}
+	if err := fstest.TestFS(a, "main.go", "src/lib.go", "big.txt", "alias.go"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestOpen_NotAnArchive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.tar")
+	os.WriteFile(path, []byte("definitely not a tar file, but long enough to need a header block"), 0644)
+	if _, err := Open(path, 0); err == nil {
+		t.Fatal("expected error for a file that is not an archive")
+	}
+}
+
+func TestWalk(t *testing.T) {
+	dir := t.TempDir()
+	archives := map[string]func(path string){
+		"proj.tar.gz": func(p string) { writeTar(t, p, true) },
+		"proj.zip":    func(p string) { writeZip(t, p) },
+	}
+
+	for name, write := range archives {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			write(path)
+			a, err := Open(path, 100)
+			if err != nil {
+				t.Fatalf("Open() error: %v", err)
+			}
+			defer a.Close()
+
+			// Regular files only, in archive order, readable while visited
+			var got []string
+			err = a.Walk(func(name string, size int64) error {
+				data, err := fs.ReadFile(a, name)
+				switch {
+				case name == "big.txt":
+					if size != 200 || !errors.Is(err, ErrTooLarge) {
+						t.Errorf("big.txt: size %d, error %v", size, err)
+					}
+				case err != nil || int64(len(data)) != size:
+					t.Errorf("%s: read %d of %d bytes, %v", name, len(data), size, err)
+				}
+				got = append(got, name)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Walk() error: %v", err)
+			}
+			if want := []string{"main.go", "src/lib.go", "big.txt"}; !slices.Equal(got, want) {
+				t.Errorf("Walk() visited %v, want %v", got, want)
+			}
+
+			stop := errors.New("stop")
+			if err := a.Walk(func(string, int64) error { return stop }); err != stop {
+				t.Errorf("Walk() error = %v, want the callback's", err)
+			}
+		})
+	}
+}
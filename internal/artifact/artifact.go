@@ -0,0 +1,159 @@
+// Package artifact writes a group of generated files, such as the badge,
+// charts and history, so that a failure or crash never leaves one of them
+// truncated.
+package artifact
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Status describes what writing an artifact would do to the file on disk.
+type Status string
+
+const (
+	Create    Status = "create"
+	Update    Status = "update"
+	Unchanged Status = "unchanged"
+)
+
+// Change is the effect of writing one artifact.
+type Change struct {
+	Path    string // file path, joined with the Set's directory
+	Status  Status
+	OldSize int64 // size of the existing file; 0 for Create
+	NewSize int64
+}
+
+// Set collects the contents of files in one directory and writes them
+// together. The zero value is not usable; call New.
+type Set struct {
+	dir   string
+	names []string
+	data  map[string][]byte
+}
+
+// New returns an empty Set for files in dir.
+func New(dir string) *Set {
+	return &Set{dir: dir, data: make(map[string][]byte)}
+}
+
+// Add stages data as the contents of the file name, relative to the Set's
+// directory. Adding a name again replaces its contents.
+func (s *Set) Add(name string, data []byte) {
+	if _, ok := s.data[name]; !ok {
+		s.names = append(s.names, name)
+	}
+	s.data[name] = data
+}
+
+// Path returns the full path of the staged file name.
+func (s *Set) Path(name string) string {
+	return filepath.Join(s.dir, name)
+}
+
+// Changes compares the staged files with those on disk, in the order they
+// were added, without writing anything.
+func (s *Set) Changes() ([]Change, error) {
+	changes := make([]Change, 0, len(s.names))
+	for _, name := range s.names {
+		c := Change{Path: s.Path(name), Status: Create, NewSize: int64(len(s.data[name]))}
+		old, err := os.ReadFile(c.Path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+		case err != nil:
+			return nil, fmt.Errorf("read %s: %w", name, err)
+		case bytes.Equal(old, s.data[name]):
+			c.Status, c.OldSize = Unchanged, int64(len(old))
+		default:
+			c.Status, c.OldSize = Update, int64(len(old))
+		}
+		changes = append(changes, c)
+	}
+	return changes, nil
+}
+
+// Commit writes every changed file to a temporary file beside it, syncs them
+// all to disk and only then renames them into place. If any write fails,
+// nothing is replaced. Each rename is atomic, so even a crash between renames
+// leaves every file either old or new, never partial. Unchanged files are not
+// touched.
+func (s *Set) Commit() ([]Change, error) {
+	changes, err := s.Changes()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return nil, fmt.Errorf("create dir: %w", err)
+	}
+
+	var temps []string
+	defer func() {
+		for _, tmp := range temps {
+			os.Remove(tmp) // no-op once renamed
+		}
+	}()
+	staged := make(map[string]string) // final path -> temp path
+	for i, c := range changes {
+		if c.Status == Unchanged {
+			continue
+		}
+		tmp, err := writeTemp(c.Path, s.data[s.names[i]])
+		if tmp != "" {
+			temps = append(temps, tmp)
+		}
+		if err != nil {
+			return nil, err
+		}
+		staged[c.Path] = tmp
+	}
+
+	for _, c := range changes {
+		if tmp, ok := staged[c.Path]; ok {
+			if err := os.Rename(tmp, c.Path); err != nil {
+				return nil, fmt.Errorf("rename %s: %w", c.Path, err)
+			}
+		}
+	}
+	if len(staged) > 0 {
+		syncDir(s.dir)
+	}
+	return changes, nil
+}
+
+// writeTemp writes data to a new hidden file beside path and flushes it to
+// disk. It returns the temporary path even on failure, so the caller can
+// remove it.
+func writeTemp(path string, data []byte) (string, error) {
+	name := filepath.Base(path)
+	f, err := os.CreateTemp(filepath.Dir(path), "."+name+".tmp-*")
+	if err != nil {
+		return "", fmt.Errorf("write %s: %w", name, err)
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Chmod(0644)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return f.Name(), fmt.Errorf("write %s: %w", name, err)
+	}
+	return f.Name(), nil
+}
+
+// syncDir flushes the renames in dir to disk where the platform allows it.
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
@@ -0,0 +1,100 @@
+package artifact
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSet_Commit(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), ".ghloc")
+	os.MkdirAll(dir, 0755)
+	os.WriteFile(filepath.Join(dir, "badge.svg"), []byte("old badge"), 0644)
+	os.WriteFile(filepath.Join(dir, "chart.svg"), []byte("same chart"), 0644)
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(filepath.Join(dir, "chart.svg"), old, old)
+
+	s := New(dir)
+	s.Add("badge.svg", []byte("new badge"))
+	s.Add("chart.svg", []byte("same chart"))
+	s.Add("history.json", []byte("[]"))
+
+	changes, err := s.Commit()
+	if err != nil {
+		t.Fatalf("Commit() error: %v", err)
+	}
+	want := []Status{Update, Unchanged, Create}
+	for i, c := range changes {
+		if c.Status != want[i] {
+			t.Errorf("%s: status %s, want %s", c.Path, c.Status, want[i])
+		}
+	}
+	if changes[0].OldSize != 9 || changes[0].NewSize != 9 {
+		t.Errorf("badge sizes = %d -> %d", changes[0].OldSize, changes[0].NewSize)
+	}
+
+	for name, content := range map[string]string{"badge.svg": "new badge", "chart.svg": "same chart", "history.json": "[]"} {
+		if data, err := os.ReadFile(filepath.Join(dir, name)); err != nil || string(data) != content {
+			t.Errorf("%s = %q, %v; want %q", name, data, err, content)
+		}
+	}
+	if info, _ := os.Stat(filepath.Join(dir, "chart.svg")); !info.ModTime().Equal(old) {
+		t.Error("unchanged file should not be rewritten")
+	}
+	if info, _ := os.Stat(filepath.Join(dir, "history.json")); info.Mode().Perm() != 0644 {
+		t.Errorf("history.json mode = %v, want 0644", info.Mode().Perm())
+	}
+	assertNoTemps(t, dir)
+}
+
+func TestSet_CommitCreatesDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "out")
+	s := New(dir)
+	s.Add("badge.svg", []byte("badge"))
+	if _, err := s.Commit(); err != nil {
+		t.Fatalf("Commit() error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "badge.svg")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSet_CommitFailureReplacesNothing(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "badge.svg"), []byte("old badge"), 0644)
+
+	s := New(dir)
+	s.Add("badge.svg", []byte("new badge"))
+	s.Add("missing/chart.svg", []byte("chart")) // its directory does not exist
+	if _, err := s.Commit(); err == nil {
+		t.Fatal("expected error writing into a missing directory")
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "badge.svg")); string(data) != "old badge" {
+		t.Errorf("badge.svg = %q; a failed commit should replace nothing", data)
+	}
+	assertNoTemps(t, dir)
+}
+
+func TestSet_Changes(t *testing.T) {
+	dir := t.TempDir()
+	s := New(dir)
+	s.Add("badge.svg", []byte("badge"))
+	changes, err := s.Changes()
+	if err != nil {
+		t.Fatalf("Changes() error: %v", err)
+	}
+	if len(changes) != 1 || changes[0].Status != Create || changes[0].Path != filepath.Join(dir, "badge.svg") {
+		t.Errorf("Changes() = %+v", changes)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Changes() should not write, found %d entries", len(entries))
+	}
+}
+
+func assertNoTemps(t *testing.T, dir string) {
+	t.Helper()
+	if temps, _ := filepath.Glob(filepath.Join(dir, ".*.tmp-*")); len(temps) > 0 {
+		t.Errorf("temporary files left behind: %v", temps)
+	}
+}
@@ -3,6 +3,8 @@ package badge
 import (
 	"strings"
 	"testing"
+
+	"github.com/rjwalters/ghloc/internal/golden"
 )
 
 func TestRenderSVG(t *testing.T) {
@@ -20,6 +22,24 @@ func TestRenderSVG(t *testing.T) {
 	}
 }
 
+func TestRenderSVG_Golden(t *testing.T) {
+	golden.Check(t, "badge.svg", RenderSVG(FormatLOC(12403)))
+}
+
+func TestRenderLabeledSVG(t *testing.T) {
+	svgStr := string(RenderLabeledSVG("complexity", "1.2k"))
+
+	if !strings.Contains(svgStr, "complexity") {
+		t.Error("SVG missing label")
+	}
+	if strings.Contains(svgStr, "lines of code") {
+		t.Error("SVG should not contain default label")
+	}
+	if !strings.Contains(svgStr, "1.2k") {
+		t.Error("SVG missing message")
+	}
+}
+
 func TestFormatLOC(t *testing.T) {
 	tests := []struct {
 		input int64
@@ -44,3 +64,14 @@ func TestFormatLOC(t *testing.T) {
 		}
 	}
 }
+
+func TestRenderLabeledSVG_Accessible(t *testing.T) {
+	svgStr := string(RenderLabeledSVG("docs", "12% & rising"))
+
+	if !strings.HasPrefix(svgStr, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width=`) {
+		t.Errorf("root element mangled: %.120s", svgStr)
+	}
+	if !strings.Contains(svgStr, `role="img" aria-label="docs: 12% &amp; rising"><title>docs: 12% &amp; rising</title>`) {
+		t.Errorf("SVG missing accessibility metadata: %.300s", svgStr)
+	}
+}
@@ -0,0 +1,71 @@
+package badge
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/narqo/go-badge"
+)
+
+// Grade is one step of a graded badge: values of at least Min get Color and,
+// when set, Label appended to the message in words, so the grade does not
+// depend on telling colors apart.
+type Grade struct {
+	Min   float64 `json:"min"`
+	Color string  `json:"color"` // a badge color name or "#rgb"/"#rrggbb"
+	Label string  `json:"label,omitempty"`
+}
+
+// Thresholds grade a value. Values below every minimum get the lowest grade.
+type Thresholds []Grade
+
+// DefaultDocThresholds grade documentation coverage, the percentage of code
+// and comment lines that are comments, with colors from the colorblind-safe
+// Okabe-Ito palette.
+var DefaultDocThresholds = Thresholds{
+	{Min: 0, Color: "#D55E00", Label: "low"},    // vermillion
+	{Min: 10, Color: "#CC79A7", Label: "fair"},  // reddish purple
+	{Min: 20, Color: "#0072B2", Label: "good"},  // blue
+	{Min: 25, Color: "#009E73", Label: "great"}, // bluish green
+}
+
+var hexColorRe = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// Validate reports colors that badges cannot render.
+func (t Thresholds) Validate() error {
+	for _, g := range t {
+		if _, ok := badge.ColorScheme[g.Color]; !ok && !hexColorRe.MatchString(g.Color) {
+			return fmt.Errorf("unknown badge color %q", g.Color)
+		}
+	}
+	return nil
+}
+
+// Grade returns the grade with the highest minimum that v reaches.
+func (t Thresholds) Grade(v float64) Grade {
+	if len(t) == 0 {
+		return Grade{Color: string(badge.ColorLightgrey)}
+	}
+	sorted := slices.SortedFunc(slices.Values(t), func(a, b Grade) int {
+		return cmp.Compare(a.Min, b.Min)
+	})
+	g := sorted[0]
+	for _, next := range sorted[1:] {
+		if v >= next.Min {
+			g = next
+		}
+	}
+	return g
+}
+
+// RenderGradedSVG renders a badge for v, colored by its grade, with the grade's
+// label after the message, e.g. "18% (fair)".
+func RenderGradedSVG(label, message string, v float64, t Thresholds) []byte {
+	g := t.Grade(v)
+	if g.Label != "" {
+		message += " (" + g.Label + ")"
+	}
+	return RenderLabeledSVG(label, message, badge.Color(g.Color))
+}
@@ -0,0 +1,53 @@
+package badge
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestThresholds_Grade(t *testing.T) {
+	tests := []struct {
+		value float64
+		want  string
+	}{
+		{0, "low"},
+		{9.9, "low"},
+		{10, "fair"},
+		{19.9, "fair"},
+		{20, "good"},
+		{60, "great"},
+	}
+	for _, tt := range tests {
+		if got := DefaultDocThresholds.Grade(tt.value); got.Label != tt.want {
+			t.Errorf("Grade(%v) = %q, want %q", tt.value, got.Label, tt.want)
+		}
+	}
+
+	// Order does not matter, and values below every minimum get the lowest grade
+	custom := Thresholds{{Min: 30, Color: "green"}, {Min: 15, Color: "yellow"}}
+	if got := custom.Grade(5).Color; got != "yellow" {
+		t.Errorf("Grade(5) = %q, want yellow", got)
+	}
+	if got := custom.Grade(31).Color; got != "green" {
+		t.Errorf("Grade(31) = %q, want green", got)
+	}
+}
+
+func TestThresholds_Validate(t *testing.T) {
+	if err := DefaultDocThresholds.Validate(); err != nil {
+		t.Errorf("defaults: %v", err)
+	}
+	if err := (Thresholds{{Color: "green"}, {Color: "#abc"}}).Validate(); err != nil {
+		t.Errorf("names and hex colors: %v", err)
+	}
+	if err := (Thresholds{{Min: 10, Color: "teal"}}).Validate(); err == nil {
+		t.Error("expected error for an unknown color")
+	}
+}
+
+func TestRenderGradedSVG(t *testing.T) {
+	svg := string(RenderGradedSVG("docs", "18%", 18, DefaultDocThresholds))
+	if !strings.Contains(svg, "18% (fair)") || !strings.Contains(svg, `fill="#CC79A7"`) {
+		t.Errorf("expected a fair grade in words and color:\n%s", svg)
+	}
+}
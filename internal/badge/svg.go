@@ -3,20 +3,41 @@ package badge
 import (
 	"bytes"
 	"fmt"
+	"html"
 
 	"github.com/narqo/go-badge"
 )
 
 // RenderSVG generates an SVG badge with the given message and color.
 func RenderSVG(message string, colors ...badge.Color) []byte {
+	return RenderLabeledSVG("lines of code", message, colors...)
+}
+
+// RenderLabeledSVG generates an SVG badge with a custom label, message and color.
+func RenderLabeledSVG(label, message string, colors ...badge.Color) []byte {
 	color := badge.ColorBlue
 	if len(colors) > 0 {
 		color = colors[0]
 	}
 
 	var buf bytes.Buffer
-	badge.Render("lines of code", message, color, &buf)
-	return buf.Bytes()
+	badge.Render(label, message, color, &buf)
+	return accessible(buf.Bytes(), label+": "+message)
+}
+
+// accessible labels a rendered badge for screen readers with an image role,
+// an aria-label and a <title>, which also shows as a tooltip.
+func accessible(svg []byte, text string) []byte {
+	end := bytes.IndexByte(svg, '>')
+	if end < 0 {
+		return svg
+	}
+	text = html.EscapeString(text)
+	var out bytes.Buffer
+	out.Write(svg[:end])
+	fmt.Fprintf(&out, ` role="img" aria-label="%s"><title>%s</title>`, text, text)
+	out.Write(svg[end+1:])
+	return out.Bytes()
 }
 
 // FormatLOC formats a LOC count for display (e.g., "12.3k", "1.5M").
@@ -1,13 +1,30 @@
 package chart
 
 import (
+	"regexp"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/rjwalters/ghloc/internal/golden"
+	"github.com/rjwalters/ghloc/internal/metric"
 	"github.com/rjwalters/ghloc/internal/store"
 )
 
+func day(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 12, 0, 0, 0, time.UTC)
+}
+
+// testHistory is a year of monthly snapshots, mostly growing, shared by the
+// history, growth and sparkline tests.
+func testHistory() []store.Snapshot {
+	var snapshots []store.Snapshot
+	for i, loc := range []int64{969, 1520, 2210, 2150, 4800, 4100, 7312, 9950, 9800, 12403} {
+		snapshots = append(snapshots, store.Snapshot{TotalLOC: loc, CreatedAt: day(2024, time.Month(1+i), 10)})
+	}
+	return snapshots
+}
+
 func TestRenderHistoryChart_WithData(t *testing.T) {
 	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
 	snapshots := []store.Snapshot{
@@ -18,7 +35,7 @@ func TestRenderHistoryChart_WithData(t *testing.T) {
 		{TotalLOC: 500, CreatedAt: base.Add(28 * 24 * time.Hour)},
 	}
 
-	svg := RenderHistoryChart(snapshots)
+	svg := RenderHistoryChart(snapshots, Options{})
 	svgStr := string(svg)
 
 	if !strings.Contains(svgStr, "<svg") {
@@ -35,8 +52,79 @@ func TestRenderHistoryChart_WithData(t *testing.T) {
 	}
 }
 
+func TestRenderHistoryChart_Golden(t *testing.T) {
+	golden.Check(t, "chart.svg", RenderHistoryChart(testHistory(), Options{GapThreshold: 90 * 24 * time.Hour}))
+}
+
+func TestCoord(t *testing.T) {
+	tests := []struct {
+		v    float64
+		want string
+	}{
+		{0, "0.0"},
+		{-0.04, "0.0"},
+		{12.25, "12.3"},
+		{-3.15, "-3.2"},
+		{359.99, "360.0"},
+	}
+	for _, tt := range tests {
+		if got := coord(tt.v); got != tt.want {
+			t.Errorf("coord(%v) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestTrendSummary(t *testing.T) {
+	jan := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	oct := time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC)
+	format := func(v float64) string { return formatAxisValue(v) }
+	tests := []struct {
+		first, last float64
+		from, to    time.Time
+		want        string
+	}{
+		{969, 12403, jan, oct, "LOC grew from 969 to 12k between Jan 2024 and Oct 2026"},
+		{500, 400, jan, oct, "LOC fell from 500 to 400 between Jan 2024 and Oct 2026"},
+		{500, 500, jan, oct, "LOC stayed at 500 between Jan 2024 and Oct 2026"},
+		{100, 500, jan, jan.Add(time.Hour), "LOC was 500 in Jan 2024"},
+	}
+	for _, tt := range tests {
+		if got := trendSummary("LOC", format, tt.first, tt.last, tt.from, tt.to); got != tt.want {
+			t.Errorf("trendSummary() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestRenderers_Accessible(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	snapshots := []store.Snapshot{
+		{TotalLOC: 969, CreatedAt: base, Languages: []store.LanguageRecord{{Language: "Go", Code: 969, Comments: 100}}},
+		{TotalLOC: 12403, CreatedAt: base.AddDate(2, 9, 0), Languages: []store.LanguageRecord{{Language: "Go", Code: 12403, Comments: 2000}}},
+	}
+	svgs := map[string][]byte{
+		"history":   RenderHistoryChart(snapshots, Options{}),
+		"growth":    RenderGrowthChart(snapshots, Options{}),
+		"density":   RenderCommentDensityChart(snapshots, Options{}),
+		"donut":     RenderLanguageDonut(snapshots[1], Options{}),
+		"bar":       RenderLanguageBar(snapshots[1], Options{}),
+		"sparkline": RenderSparkline(snapshots, 0),
+		"badge":     RenderSparklineBadge(snapshots, 0),
+		"empty":     RenderHistoryChart(nil, Options{}),
+	}
+	for name, svg := range svgs {
+		s := string(svg)
+		if !regexp.MustCompile(`^<svg [^>]*role="img" aria-label="[^"]+"><title>[^<]+</title>`).MatchString(s) {
+			t.Errorf("%s: missing role, aria-label or title: %.200s", name, s)
+		}
+	}
+	if s := string(svgs["history"]); !strings.Contains(s, "<desc>Lines of Code grew from 969 to 12.4k between Jan 2024 and Oct 2026</desc>") ||
+		!strings.Contains(s, "<title>2026-10-01: 12.4k</title>") {
+		t.Errorf("history chart missing summary or point titles:\n%s", s)
+	}
+}
+
 func TestRenderHistoryChart_NoData(t *testing.T) {
-	svg := RenderHistoryChart(nil)
+	svg := RenderHistoryChart(nil, Options{})
 	svgStr := string(svg)
 
 	if !strings.Contains(svgStr, "No data yet") {
@@ -49,7 +137,7 @@ func TestRenderHistoryChart_SinglePoint(t *testing.T) {
 		{TotalLOC: 500, CreatedAt: time.Now()},
 	}
 
-	svg := RenderHistoryChart(snapshots)
+	svg := RenderHistoryChart(snapshots, Options{})
 	if len(svg) == 0 {
 		t.Fatal("expected non-empty SVG")
 	}
@@ -66,7 +154,7 @@ func TestRenderHistoryChart_LargeValues(t *testing.T) {
 		{TotalLOC: 1200000, CreatedAt: base.Add(60 * 24 * time.Hour)},
 	}
 
-	svg := RenderHistoryChart(snapshots)
+	svg := RenderHistoryChart(snapshots, Options{})
 	svgStr := string(svg)
 
 	if !strings.Contains(svgStr, "<path") {
@@ -97,6 +185,9 @@ func TestFormatAxisValue(t *testing.T) {
 		{1500, "2k"},
 		{50000, "50k"},
 		{1500000, "1.5M"},
+		{0.30000000000000004, "0.3"},
+		{2.5, "2.5"},
+		{0, "0"},
 	}
 	for _, tt := range tests {
 		got := formatAxisValue(tt.input)
@@ -105,3 +196,112 @@ func TestFormatAxisValue(t *testing.T) {
 		}
 	}
 }
+
+func TestRenderHistoryChart_OutOfOrder(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	snapshots := []store.Snapshot{
+		{TotalLOC: 300, CreatedAt: base.Add(14 * 24 * time.Hour)},
+		{TotalLOC: 100, CreatedAt: base},
+		{TotalLOC: 200, CreatedAt: base.Add(7 * 24 * time.Hour)},
+	}
+
+	svgStr := string(RenderHistoryChart(snapshots, Options{}))
+
+	if strings.Contains(svgStr, `cx="-`) {
+		t.Error("chart contains negative x coordinates")
+	}
+	if strings.Count(svgStr, "<circle") != 3 {
+		t.Errorf("expected 3 data points, got %d", strings.Count(svgStr, "<circle"))
+	}
+}
+
+func TestNormalizeSnapshots(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	snapshots := []store.Snapshot{
+		{TotalLOC: 200, CreatedAt: base.Add(time.Hour)},
+		{TotalLOC: 100, CreatedAt: base},
+		{TotalLOC: 150, CreatedAt: base},
+	}
+
+	got := normalizeSnapshots(snapshots)
+
+	if len(got) != 2 {
+		t.Fatalf("expected 2 snapshots, got %d", len(got))
+	}
+	if got[0].TotalLOC != 150 {
+		t.Errorf("duplicate timestamp should keep last snapshot: got %d, want 150", got[0].TotalLOC)
+	}
+	if got[1].TotalLOC != 200 {
+		t.Errorf("second snapshot TotalLOC: got %d, want 200", got[1].TotalLOC)
+	}
+	if snapshots[0].TotalLOC != 200 {
+		t.Error("normalizeSnapshots modified its input")
+	}
+}
+
+func TestRenderHistoryChart_GapThreshold(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	snapshots := []store.Snapshot{
+		{TotalLOC: 100, CreatedAt: base},
+		{TotalLOC: 120, CreatedAt: base.Add(day)},
+		{TotalLOC: 400, CreatedAt: base.Add(90 * day)},
+		{TotalLOC: 420, CreatedAt: base.Add(91 * day)},
+	}
+
+	continuous := string(RenderHistoryChart(snapshots, Options{}))
+	broken := string(RenderHistoryChart(snapshots, Options{GapThreshold: 30 * day}))
+
+	moves := regexp.MustCompile(`[" ]M\d`)
+	if got := len(moves.FindAllString(continuous, -1)); got != 2 {
+		t.Errorf("continuous chart: expected 2 move commands (area + line), got %d", got)
+	}
+	if got := len(moves.FindAllString(broken, -1)); got != 4 {
+		t.Errorf("gapped chart: expected 4 move commands (2 areas + 2 line segments), got %d", got)
+	}
+}
+
+func TestSplitAtGaps(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	times := []time.Time{base, base.Add(time.Hour), base.Add(10 * time.Hour), base.Add(11 * time.Hour)}
+
+	if got := splitAtGaps(times, 0); len(got) != 1 {
+		t.Errorf("zero threshold: expected 1 segment, got %v", got)
+	}
+	got := splitAtGaps(times, 2*time.Hour)
+	want := [][2]int{{0, 2}, {2, 4}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("splitAtGaps = %v, want %v", got, want)
+	}
+}
+
+func TestRenderMetricChart_Complexity(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	snapshots := []store.Snapshot{
+		{TotalLOC: 100, CreatedAt: base}, // predates complexity tracking
+		{TotalLOC: 200, TotalComplexity: 20, CreatedAt: base.Add(24 * time.Hour)},
+		{TotalLOC: 400, TotalComplexity: 50, CreatedAt: base.Add(48 * time.Hour)},
+	}
+
+	m, err := metric.Lookup("complexity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	svgStr := string(RenderMetricChart(snapshots, m, Options{}))
+
+	if !strings.Contains(svgStr, "Cyclomatic Complexity") {
+		t.Error("chart missing metric title")
+	}
+	if got := strings.Count(svgStr, "<circle"); got != 2 {
+		t.Errorf("expected 2 data points (snapshot without complexity skipped), got %d", got)
+	}
+}
+
+func TestRenderMetricChart_NoValues(t *testing.T) {
+	m, _ := metric.Lookup("complexity")
+	svg := RenderMetricChart([]store.Snapshot{{TotalLOC: 100, CreatedAt: time.Now()}}, m, Options{})
+
+	if !strings.Contains(string(svg), "No data yet") {
+		t.Error("chart without metric values should contain 'No data yet'")
+	}
+}
@@ -0,0 +1,240 @@
+package chart
+
+import (
+	"cmp"
+	"fmt"
+	"html"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rjwalters/ghloc/internal/metric"
+	"github.com/rjwalters/ghloc/internal/store"
+)
+
+// defaultDensityLanguages caps the per-language lines on the comment density
+// chart when Options.MaxLanguages is zero; more become hard to tell apart.
+const defaultDensityLanguages = 5
+
+// overallColor draws the all-languages series.
+const overallColor = "#333"
+
+// seriesDashes tells the lines of a multi-series chart apart without relying
+// on color. The first series is solid.
+var seriesDashes = []string{"", "6 3", "2 3", "8 3 2 3", "1 3", "10 4"}
+
+// series is one line on a multi-series chart. Points without a value are
+// NaN and break the line.
+type series struct {
+	Name   string
+	Color  string
+	Dash   string // stroke-dasharray; empty for a solid line
+	Width  float64
+	Values []float64
+}
+
+// dashAttr returns the stroke-dasharray attribute for a series, if any.
+func (sr series) dashAttr() string {
+	if sr.Dash == "" {
+		return ""
+	}
+	return ` stroke-dasharray="` + sr.Dash + `"`
+}
+
+// densitySeries returns the overall comment density of each snapshot,
+// followed by one series per language that has comments in the latest
+// snapshot, largest first.
+func densitySeries(snapshots []store.Snapshot, opt Options) []series {
+	overall := series{Name: "All languages", Color: overallColor, Width: 2.5}
+	density, _ := metric.Lookup("comment-density")
+	for _, s := range snapshots {
+		v, ok := density.Value(s)
+		if !ok {
+			v = math.NaN()
+		}
+		overall.Values = append(overall.Values, v)
+	}
+
+	var langs []store.LanguageRecord
+	if len(snapshots) > 0 {
+		for _, l := range snapshots[len(snapshots)-1].Languages {
+			if l.Comments > 0 {
+				langs = append(langs, l)
+			}
+		}
+	}
+	slices.SortFunc(langs, func(a, b store.LanguageRecord) int {
+		if c := cmp.Compare(b.Code, a.Code); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Language, b.Language)
+	})
+	limit := opt.MaxLanguages
+	if limit == 0 {
+		limit = defaultDensityLanguages
+	}
+	if len(langs) > limit {
+		langs = langs[:limit]
+	}
+
+	out := []series{overall}
+	for i, l := range langs {
+		sr := series{Name: l.Language, Color: sharePalette[i%len(sharePalette)], Dash: seriesDashes[(i+1)%len(seriesDashes)], Width: 1.5}
+		for _, s := range snapshots {
+			v := math.NaN()
+			if k := slices.IndexFunc(s.Languages, func(r store.LanguageRecord) bool { return r.Language == l.Language }); k >= 0 {
+				if d, ok := metric.CommentDensity(s.Languages[k].Code, s.Languages[k].Comments); ok {
+					v = d
+				}
+			}
+			sr.Values = append(sr.Values, v)
+		}
+		out = append(out, sr)
+	}
+	return out
+}
+
+// densitySummary describes the overall trend and the latest density of each
+// language.
+func densitySummary(all []series, from, to time.Time) string {
+	var first, last float64 = math.NaN(), math.NaN()
+	for _, v := range all[0].Values {
+		if !math.IsNaN(v) {
+			if math.IsNaN(first) {
+				first = v
+			}
+			last = v
+		}
+	}
+	summary := "No overall comment density"
+	if !math.IsNaN(first) {
+		summary = trendSummary("Comment density", formatDensity, first, last, from, to)
+	}
+	var latest []string
+	for _, sr := range all[1:] {
+		if v := sr.Values[len(sr.Values)-1]; !math.IsNaN(v) {
+			latest = append(latest, sr.Name+" "+formatDensity(v))
+		}
+	}
+	if len(latest) > 0 {
+		summary += "; latest by language: " + strings.Join(latest, ", ")
+	}
+	return summary
+}
+
+// formatDensity formats a comment density percentage.
+func formatDensity(v float64) string {
+	return fmt.Sprintf("%.1f%%", v)
+}
+
+// RenderCommentDensityChart renders comments as a percentage of code and
+// comment lines over time, overall and for the largest commented languages.
+func RenderCommentDensityChart(snapshots []store.Snapshot, opt Options) []byte {
+	sorted := normalizeSnapshots(snapshots)
+	all := densitySeries(sorted, opt)
+
+	maxVal := math.Inf(-1)
+	for _, sr := range all {
+		for _, v := range sr.Values {
+			if !math.IsNaN(v) {
+				maxVal = math.Max(maxVal, v)
+			}
+		}
+	}
+	if math.IsInf(maxVal, -1) {
+		return []byte(emptySVG(800, 400))
+	}
+
+	const (
+		width       = 800
+		height      = 400
+		marginTop   = 40
+		marginRight = 160 // room for the legend
+		marginBot   = 60
+		marginLeft  = 80
+		plotW       = width - marginLeft - marginRight
+		plotH       = height - marginTop - marginBot
+	)
+
+	yMax := math.Min(100, maxVal*1.1)
+	if yMax == 0 {
+		yMax = 10
+	}
+	tMin, tMax := sorted[0].CreatedAt, sorted[len(sorted)-1].CreatedAt
+	tRange := tMax.Sub(tMin).Seconds()
+	if tRange == 0 {
+		tRange = 86400 // 1 day minimum
+	}
+	xOf := func(t time.Time) float64 { return marginLeft + t.Sub(tMin).Seconds()/tRange*plotW }
+	yOf := func(v float64) float64 { return marginTop + plotH - v/yMax*plotH }
+
+	const title = "Comment Density (% of code and comment lines)"
+	var sb strings.Builder
+	sb.WriteString(svgOpen(width, height, title, densitySummary(all, tMin, tMax)))
+	sb.WriteString("\n")
+	sb.WriteString(fmt.Sprintf(`<rect width="%d" height="%d" fill="white"/>`, width, height))
+	sb.WriteString("\n")
+
+	// Y-axis grid lines and labels
+	for _, tick := range niceAxisTicks(0, yMax, 5) {
+		y := yOf(tick)
+		sb.WriteString(fmt.Sprintf(`<line x1="%d" y1="%s" x2="%d" y2="%s" stroke="#E5E5E5" stroke-width="1"/>`, marginLeft, coord(y), width-marginRight, coord(y)))
+		sb.WriteString("\n")
+		sb.WriteString(fmt.Sprintf(`<text x="%d" y="%s" text-anchor="end" font-family="system-ui, sans-serif" font-size="12" fill="#666">%s%%</text>`, marginLeft-8, coord(y+4), formatAxisValue(tick)))
+		sb.WriteString("\n")
+	}
+
+	// X-axis date labels
+	for _, t := range dateAxisTicks(tMin, tMax, 6) {
+		sb.WriteString(fmt.Sprintf(`<text x="%s" y="%d" text-anchor="middle" font-family="system-ui, sans-serif" font-size="12" fill="#666">%s</text>`, coord(xOf(t)), height-marginBot+20, t.Format("Jan 2006")))
+		sb.WriteString("\n")
+	}
+
+	// Languages first so the overall line is drawn on top
+	for i := len(all) - 1; i >= 0; i-- {
+		sr := all[i]
+		var d []string
+		cmd := "M"
+		for k, v := range sr.Values {
+			if math.IsNaN(v) {
+				cmd = "M"
+				continue
+			}
+			d = append(d, cmd+coord(xOf(sorted[k].CreatedAt))+","+coord(yOf(v)))
+			cmd = "L"
+		}
+		if len(d) == 0 {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf(`<path d="%s" fill="none" stroke="%s" stroke-width="%s"%s stroke-linejoin="round"/>`,
+			strings.Join(d, " "), sr.Color, coord(sr.Width), sr.dashAttr()))
+		sb.WriteString("\n")
+		for k, v := range sr.Values {
+			if math.IsNaN(v) {
+				continue
+			}
+			sb.WriteString(fmt.Sprintf(`<circle cx="%s" cy="%s" r="2.5" fill="%s"><title>%s, %s: %s</title></circle>`,
+				coord(xOf(sorted[k].CreatedAt)), coord(yOf(v)), sr.Color, html.EscapeString(sr.Name), sorted[k].CreatedAt.Format(time.DateOnly), formatDensity(v)))
+		}
+		sb.WriteString("\n")
+	}
+
+	// Legend
+	for i, sr := range all {
+		y := marginTop + 10 + i*22
+		sb.WriteString(fmt.Sprintf(`<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="%s" stroke-width="%s"%s/>`, width-marginRight+16, y, width-marginRight+36, y, sr.Color, coord(sr.Width), sr.dashAttr()))
+		sb.WriteString(fmt.Sprintf(`<text x="%d" y="%d" font-family="system-ui, sans-serif" font-size="12" fill="#333">%s</text>`, width-marginRight+42, y+4, html.EscapeString(sr.Name)))
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString(fmt.Sprintf(`<text x="%d" y="24" font-family="system-ui, sans-serif" font-size="16" font-weight="600" fill="#333">%s</text>`, marginLeft, html.EscapeString(title)))
+	sb.WriteString("\n")
+
+	// Axes
+	sb.WriteString(fmt.Sprintf(`<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#CCC" stroke-width="1"/>`, marginLeft, marginTop, marginLeft, marginTop+plotH))
+	sb.WriteString(fmt.Sprintf(`<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#CCC" stroke-width="1"/>`, marginLeft, marginTop+plotH, width-marginRight, marginTop+plotH))
+
+	sb.WriteString("\n</svg>")
+	return []byte(sb.String())
+}
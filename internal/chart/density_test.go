@@ -0,0 +1,60 @@
+package chart
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rjwalters/ghloc/internal/golden"
+	"github.com/rjwalters/ghloc/internal/store"
+)
+
+func densityHistory() []store.Snapshot {
+	var snapshots []store.Snapshot
+	for i := range 6 {
+		s := store.Snapshot{
+			CreatedAt: day(2024, time.Month(1+2*i), 1),
+			Languages: []store.LanguageRecord{
+				{Language: "Go", Code: int64(1000 + 400*i), Comments: int64(120 + 80*i)},
+				{Language: "Markdown", Code: 300}, // no comments, so not charted
+			},
+		}
+		if i >= 2 { // Python arrives later
+			s.Languages = append(s.Languages, store.LanguageRecord{Language: "Python", Code: int64(200 * i), Comments: int64(10 * i * i)})
+		}
+		snapshots = append(snapshots, s)
+	}
+	return snapshots
+}
+
+func TestDensitySeries(t *testing.T) {
+	all := densitySeries(densityHistory(), Options{})
+	var names []string
+	for _, sr := range all {
+		names = append(names, sr.Name)
+	}
+	if got := strings.Join(names, ","); got != "All languages,Go,Python" {
+		t.Fatalf("series = %s", got)
+	}
+	if v := all[1].Values[0]; math.Abs(v-120.0/1120*100) > 1e-9 {
+		t.Errorf("Go density = %v", v)
+	}
+	if !math.IsNaN(all[2].Values[0]) || math.IsNaN(all[2].Values[2]) {
+		t.Errorf("Python should have no value before it appears: %v", all[2].Values)
+	}
+	if got := densitySeries(densityHistory(), Options{MaxLanguages: 1}); len(got) != 2 {
+		t.Errorf("MaxLanguages 1: got %d series, want 2", len(got))
+	}
+}
+
+func TestRenderCommentDensityChart_Golden(t *testing.T) {
+	golden.Check(t, "comment-density.svg", RenderCommentDensityChart(densityHistory(), Options{}))
+}
+
+func TestRenderCommentDensityChart_Empty(t *testing.T) {
+	s := store.Snapshot{Languages: []store.LanguageRecord{{Language: "Go"}}}
+	if svg := string(RenderCommentDensityChart([]store.Snapshot{s}, Options{})); !strings.Contains(svg, "No data yet") {
+		t.Error("expected placeholder for snapshots without code or comments")
+	}
+}
@@ -0,0 +1,256 @@
+package chart
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/rjwalters/ghloc/internal/badge"
+	"github.com/rjwalters/ghloc/internal/store"
+)
+
+// Period is the bucket size of a growth chart.
+type Period string
+
+const (
+	Weekly  Period = "week"
+	Monthly Period = "month" // the default
+)
+
+// ParsePeriod validates a period name; "" means Monthly.
+func ParsePeriod(s string) (Period, error) {
+	switch p := Period(s); p {
+	case "":
+		return Monthly, nil
+	case Weekly, Monthly:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown period %q (want week or month)", s)
+}
+
+// start returns the beginning of the period containing t, in UTC. Weeks
+// start on Monday.
+func (p Period) start(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	if p == Weekly {
+		day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	}
+	return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func (p Period) next(t time.Time) time.Time {
+	if p == Weekly {
+		return t.AddDate(0, 0, 7)
+	}
+	return t.AddDate(0, 1, 0)
+}
+
+// periodChange is the net change in lines of code within one period.
+type periodChange struct {
+	Start time.Time
+	Delta int64
+}
+
+// netChanges attributes the difference between each pair of consecutive
+// snapshots to the period of the later one. Every period from the first
+// snapshot to the last is present, with quiet periods at zero.
+func netChanges(snapshots []store.Snapshot, p Period) []periodChange {
+	sorted := normalizeSnapshots(snapshots)
+	if len(sorted) < 2 {
+		return nil
+	}
+	var changes []periodChange
+	last := p.start(sorted[len(sorted)-1].CreatedAt)
+	for start := p.start(sorted[0].CreatedAt); !start.After(last); start = p.next(start) {
+		changes = append(changes, periodChange{Start: start})
+	}
+	i := 0
+	for k := 1; k < len(sorted); k++ {
+		start := p.start(sorted[k].CreatedAt)
+		for !changes[i].Start.Equal(start) {
+			i++
+		}
+		changes[i].Delta += sorted[k].TotalLOC - sorted[k-1].TotalLOC
+	}
+	return changes
+}
+
+// rollingAverage returns the mean of each value and up to window-1 values
+// before it.
+func rollingAverage(changes []periodChange, window int) []float64 {
+	avg := make([]float64, len(changes))
+	var sum int64
+	for i, c := range changes {
+		sum += c.Delta
+		if i >= window {
+			sum -= changes[i-window].Delta
+		}
+		avg[i] = float64(sum) / float64(min(i+1, window))
+	}
+	return avg
+}
+
+// RenderGrowthChart renders the net change in lines of code per week or month,
+// computed from consecutive snapshots, as bars: blue above the zero line for
+// growth and vermillion below it for shrinkage. Options.RollingAverage adds a
+// trend line.
+func RenderGrowthChart(snapshots []store.Snapshot, opt Options) []byte {
+	period := opt.GrowthPeriod
+	if period == "" {
+		period = Monthly
+	}
+	changes := netChanges(snapshots, period)
+	if len(changes) == 0 {
+		return []byte(emptySVG(800, 400))
+	}
+
+	const (
+		width       = 800
+		height      = 400
+		marginTop   = 40
+		marginRight = 40
+		marginBot   = 60
+		marginLeft  = 80
+		plotW       = width - marginLeft - marginRight
+		plotH       = height - marginTop - marginBot
+		growColor   = "#0072B2" // blue and vermillion from the colorblind-safe
+		shrinkColor = "#D55E00" // Okabe-Ito palette, unlike green and red
+	)
+
+	// The zero line is always in range
+	var minVal, maxVal float64
+	for _, c := range changes {
+		minVal = math.Min(minVal, float64(c.Delta))
+		maxVal = math.Max(maxVal, float64(c.Delta))
+	}
+	yRange := maxVal - minVal
+	if yRange == 0 {
+		yRange = 100
+	}
+	yMin, yMax := minVal, maxVal
+	if yMin < 0 {
+		yMin -= yRange * 0.1
+	}
+	if yMax > 0 || yMin == 0 {
+		yMax += yRange * 0.1
+	}
+	yOf := func(v float64) float64 {
+		return marginTop + plotH - (v-yMin)/(yMax-yMin)*plotH
+	}
+	layout := "Jan 2006"
+	if period == Weekly {
+		layout = "Jan 2"
+	}
+	slot := float64(plotW) / float64(len(changes))
+	xOf := func(i int) float64 { return marginLeft + slot*(float64(i)+0.5) } // bar center
+
+	// Title
+	title := "Net Lines of Code Change per " + strings.ToUpper(string(period[:1])) + string(period[1:])
+	if opt.RollingAverage > 1 {
+		title += fmt.Sprintf(" (line: %d-%s average)", opt.RollingAverage, period)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(svgOpen(width, height, title, growthSummary(changes, period, layout)))
+	sb.WriteString("\n")
+	sb.WriteString(fmt.Sprintf(`<rect width="%d" height="%d" fill="white"/>`, width, height))
+	sb.WriteString("\n")
+
+	// Y-axis grid lines and labels
+	for _, tick := range niceAxisTicks(yMin, yMax, 5) {
+		y := yOf(tick)
+		sb.WriteString(fmt.Sprintf(`<line x1="%d" y1="%s" x2="%d" y2="%s" stroke="#E5E5E5" stroke-width="1"/>`, marginLeft, coord(y), width-marginRight, coord(y)))
+		sb.WriteString("\n")
+		sb.WriteString(fmt.Sprintf(`<text x="%d" y="%s" text-anchor="end" font-family="system-ui, sans-serif" font-size="12" fill="#666">%s</text>`, marginLeft-8, coord(y+4), formatSignedAxisValue(tick)))
+		sb.WriteString("\n")
+	}
+
+	// X-axis period labels, thinned to about eight
+	every := (len(changes) + 7) / 8
+	for i, c := range changes {
+		if i%every != 0 {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf(`<text x="%s" y="%d" text-anchor="middle" font-family="system-ui, sans-serif" font-size="12" fill="#666">%s</text>`, coord(xOf(i)), height-marginBot+20, c.Start.Format(layout)))
+		sb.WriteString("\n")
+	}
+
+	// Bars grow up or down from the zero line
+	zero := yOf(0)
+	barW := math.Max(slot*0.7, 1)
+	for i, c := range changes {
+		if c.Delta == 0 {
+			continue
+		}
+		y, h, color := yOf(float64(c.Delta)), zero-yOf(float64(c.Delta)), growColor
+		if c.Delta < 0 {
+			y, h, color = zero, yOf(float64(c.Delta))-zero, shrinkColor
+		}
+		sb.WriteString(fmt.Sprintf(`<rect x="%s" y="%s" width="%s" height="%s" fill="%s"><title>%s %s: %s</title></rect>`,
+			coord(xOf(i)-barW/2), coord(y), coord(barW), coord(h), color, period, c.Start.Format(layout), formatDelta(c.Delta)))
+		sb.WriteString("\n")
+	}
+
+	// Rolling average overlay
+	if opt.RollingAverage > 1 {
+		sb.WriteString(`<path d="`)
+		for i, v := range rollingAverage(changes, opt.RollingAverage) {
+			cmd := " L"
+			if i == 0 {
+				cmd = "M"
+			}
+			sb.WriteString(fmt.Sprintf("%s%s,%s", cmd, coord(xOf(i)), coord(yOf(v))))
+		}
+		sb.WriteString(`" fill="none" stroke="#333" stroke-width="2" stroke-linejoin="round" stroke-linecap="round"/>`)
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString(fmt.Sprintf(`<text x="%d" y="24" font-family="system-ui, sans-serif" font-size="16" font-weight="600" fill="#333">%s</text>`, marginLeft, title))
+	sb.WriteString("\n")
+
+	// Axes, with the zero line in place of the bottom axis
+	sb.WriteString(fmt.Sprintf(`<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#CCC" stroke-width="1"/>`, marginLeft, marginTop, marginLeft, marginTop+plotH))
+	sb.WriteString(fmt.Sprintf(`<line x1="%d" y1="%s" x2="%d" y2="%s" stroke="#999" stroke-width="1"/>`, marginLeft, coord(zero), width-marginRight, coord(zero)))
+
+	sb.WriteString("\n</svg>")
+	return []byte(sb.String())
+}
+
+// growthSummary describes the net change over all periods and how many of
+// them grew or shrank.
+func growthSummary(changes []periodChange, p Period, layout string) string {
+	var net int64
+	var grew, shrank int
+	for _, c := range changes {
+		net += c.Delta
+		switch {
+		case c.Delta > 0:
+			grew++
+		case c.Delta < 0:
+			shrank++
+		}
+	}
+	return fmt.Sprintf("Lines of code changed by %s from %s to %s: %d %ss grew, %d shrank",
+		formatDelta(net), changes[0].Start.Format(layout), changes[len(changes)-1].Start.Format(layout), grew, p, shrank)
+}
+
+// formatDelta formats a change in lines of code with its sign, e.g. "+1.2k".
+func formatDelta(d int64) string {
+	switch {
+	case d < 0:
+		return "-" + badge.FormatLOC(-d)
+	case d > 0:
+		return "+" + badge.FormatLOC(d)
+	}
+	return "0"
+}
+
+// formatSignedAxisValue formats axis labels that may be negative.
+func formatSignedAxisValue(v float64) string {
+	if v < 0 {
+		return "-" + formatAxisValue(-v)
+	}
+	return formatAxisValue(v)
+}
@@ -0,0 +1,96 @@
+package chart
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rjwalters/ghloc/internal/golden"
+	"github.com/rjwalters/ghloc/internal/store"
+)
+
+func TestNetChanges_Monthly(t *testing.T) {
+	snapshots := []store.Snapshot{
+		{TotalLOC: 100, CreatedAt: day(2024, 1, 5)},
+		{TotalLOC: 150, CreatedAt: day(2024, 1, 20)},
+		{TotalLOC: 400, CreatedAt: day(2024, 2, 3)},
+		{TotalLOC: 300, CreatedAt: day(2024, 4, 30)}, // nothing recorded in March
+		{TotalLOC: 320, CreatedAt: day(2024, 4, 30).Add(time.Hour)},
+	}
+	got := netChanges(snapshots, Monthly)
+	want := []periodChange{
+		{Start: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Delta: 50},
+		{Start: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), Delta: 250},
+		{Start: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Delta: 0},
+		{Start: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), Delta: -80},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("netChanges() = %v, want %v", got, want)
+	}
+}
+
+func TestNetChanges_Weekly(t *testing.T) {
+	snapshots := []store.Snapshot{
+		{TotalLOC: 100, CreatedAt: day(2024, 1, 7)}, // Sunday
+		{TotalLOC: 130, CreatedAt: day(2024, 1, 8)}, // Monday starts a new week
+		{TotalLOC: 120, CreatedAt: day(2024, 1, 14)},
+	}
+	got := netChanges(snapshots, Weekly)
+	want := []periodChange{
+		{Start: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Delta: 0},
+		{Start: time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC), Delta: 20},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("netChanges() = %v, want %v", got, want)
+	}
+	if netChanges(snapshots[:1], Weekly) != nil {
+		t.Error("a single snapshot has no changes")
+	}
+}
+
+func TestRollingAverage(t *testing.T) {
+	changes := []periodChange{{Delta: 10}, {Delta: 20}, {Delta: -30}, {Delta: 60}}
+	if got, want := rollingAverage(changes, 2), []float64{10, 15, -5, 15}; !slices.Equal(got, want) {
+		t.Errorf("rollingAverage() = %v, want %v", got, want)
+	}
+}
+
+func TestParsePeriod(t *testing.T) {
+	for in, want := range map[string]Period{"": Monthly, "week": Weekly, "month": Monthly} {
+		if got, err := ParsePeriod(in); err != nil || got != want {
+			t.Errorf("ParsePeriod(%q) = %q, %v", in, got, err)
+		}
+	}
+	if _, err := ParsePeriod("fortnight"); err == nil {
+		t.Error("expected error for an unknown period")
+	}
+}
+
+func TestRenderGrowthChart_Golden(t *testing.T) {
+	golden.Check(t, "growth.svg", RenderGrowthChart(testHistory(), Options{RollingAverage: 3}))
+}
+
+func TestRenderGrowthChart(t *testing.T) {
+	svg := string(RenderGrowthChart(testHistory(), Options{}))
+	if !strings.Contains(svg, "per Month") {
+		t.Error("chart missing monthly title")
+	}
+	if strings.Count(svg, `fill="#0072B2"`) != 6 || strings.Count(svg, `fill="#D55E00"`) != 3 {
+		t.Errorf("expected 6 growth and 3 shrink bars:\n%s", svg)
+	}
+	if strings.Contains(svg, `stroke="#333"`) {
+		t.Error("rolling average should be off by default")
+	}
+	if !strings.Contains(svg, ">-") {
+		t.Error("expected negative axis labels")
+	}
+
+	weekly := string(RenderGrowthChart(testHistory()[:3], Options{GrowthPeriod: Weekly}))
+	if !strings.Contains(weekly, "per Week") {
+		t.Error("chart missing weekly title")
+	}
+	if empty := string(RenderGrowthChart(testHistory()[:1], Options{})); !strings.Contains(empty, "No data yet") {
+		t.Error("expected placeholder with a single snapshot")
+	}
+}
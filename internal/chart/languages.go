@@ -0,0 +1,253 @@
+package chart
+
+import (
+	"cmp"
+	"fmt"
+	"html"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/rjwalters/ghloc/internal/badge"
+	"github.com/rjwalters/ghloc/internal/store"
+)
+
+// otherLanguage names the slice that small languages are folded into.
+const otherLanguage = "Other"
+
+// sharePalette colors languages in order of size; otherColor is for "Other".
+// It is the Okabe-Ito palette, which stays distinguishable with the common
+// forms of color blindness.
+var sharePalette = []string{"#0072B2", "#E69F00", "#009E73", "#CC79A7", "#56B4E9", "#D55E00", "#F0E442", "#000000"}
+
+const otherColor = "#B0B0B0"
+
+// share is one language's part of the code in a snapshot.
+type share struct {
+	Language string
+	Code     int64
+	Fraction float64
+	Color    string
+}
+
+// languageShares returns the languages of s that have code, largest first,
+// folding those below the options' limits into a trailing "Other".
+func languageShares(s store.Snapshot, opt Options) []share {
+	minShare := opt.MinShare
+	if minShare == 0 {
+		minShare = DefaultMinShare
+	}
+	maxLanguages := opt.MaxLanguages
+	if maxLanguages == 0 {
+		maxLanguages = DefaultMaxLanguages
+	}
+
+	var langs []store.LanguageRecord
+	var total int64
+	for _, l := range s.Languages {
+		if l.Code > 0 {
+			langs = append(langs, l)
+			total += l.Code
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+	slices.SortFunc(langs, func(a, b store.LanguageRecord) int {
+		if c := cmp.Compare(b.Code, a.Code); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Language, b.Language)
+	})
+
+	// Folding a single language into "Other" would only hide its name
+	keep := len(langs)
+	for i, l := range langs {
+		if i >= maxLanguages || float64(l.Code)/float64(total) < minShare {
+			keep = i
+			break
+		}
+	}
+	if keep == len(langs)-1 {
+		keep = len(langs)
+	}
+
+	var shares []share
+	for i, l := range langs[:keep] {
+		shares = append(shares, share{
+			Language: l.Language,
+			Code:     l.Code,
+			Fraction: float64(l.Code) / float64(total),
+			Color:    sharePalette[i%len(sharePalette)],
+		})
+	}
+	if keep < len(langs) {
+		var other int64
+		for _, l := range langs[keep:] {
+			other += l.Code
+		}
+		shares = append(shares, share{
+			Language: otherLanguage,
+			Code:     other,
+			Fraction: float64(other) / float64(total),
+			Color:    otherColor,
+		})
+	}
+	return shares
+}
+
+// formatShare formats a fraction as a percentage for legends.
+func formatShare(f float64) string {
+	if f > 0 && f < 0.001 {
+		return "<0.1%"
+	}
+	return fmt.Sprintf("%.1f%%", f*100)
+}
+
+// sharesSummary lists each language's part of the code, e.g. "Go 72.6%,
+// Markdown 12.1%".
+func sharesSummary(shares []share) string {
+	parts := make([]string, len(shares))
+	for i, sh := range shares {
+		parts[i] = sh.Language + " " + formatShare(sh.Fraction)
+	}
+	return "Lines of code by language: " + strings.Join(parts, ", ")
+}
+
+// shareTitle labels one slice or segment for tooltips and screen readers.
+func shareTitle(sh share) string {
+	return fmt.Sprintf("<title>%s: %s lines (%s)</title>", html.EscapeString(sh.Language), badge.FormatLOC(sh.Code), formatShare(sh.Fraction))
+}
+
+// RenderLanguageDonut renders the language composition of a snapshot, by
+// lines of code, as a donut chart with a legend.
+func RenderLanguageDonut(snapshot store.Snapshot, opt Options) []byte {
+	shares := languageShares(snapshot, opt)
+
+	const (
+		width     = 480
+		marginTop = 40
+		rowH      = 24
+		radius    = 80.0 // of the middle of the ring
+		ringW     = 32
+		cx        = 130
+		legendX   = 260
+	)
+	height := max(260, marginTop+20+len(shares)*rowH)
+	if len(shares) == 0 {
+		return []byte(emptySVG(width, 260))
+	}
+	cy := float64(marginTop + (height-marginTop)/2)
+
+	var sb strings.Builder
+	sb.WriteString(svgOpen(width, height, "Languages", sharesSummary(shares)))
+	sb.WriteString("\n")
+	sb.WriteString(fmt.Sprintf(`<rect width="%d" height="%d" fill="white"/>`, width, height))
+	sb.WriteString("\n")
+	sb.WriteString(`<text x="20" y="24" font-family="system-ui, sans-serif" font-size="16" font-weight="600" fill="#333">Languages</text>`)
+	sb.WriteString("\n")
+
+	// Ring slices are dashes along a circle starting at 12 o'clock
+	circumference := 2 * math.Pi * radius
+	var offset, total float64
+	for _, sh := range shares {
+		total += float64(sh.Code)
+	}
+	for _, sh := range shares {
+		length := sh.Fraction * circumference
+		sb.WriteString(fmt.Sprintf(`<circle cx="%d" cy="%s" r="%s" fill="none" stroke="%s" stroke-width="%d" stroke-dasharray="%s %s" stroke-dashoffset="%s" transform="rotate(-90 %d %s)">%s</circle>`,
+			cx, coord(cy), coord(radius), sh.Color, ringW, coord(length), coord(circumference-length), coord(-offset), cx, coord(cy), shareTitle(sh)))
+		sb.WriteString("\n")
+		offset += length
+	}
+
+	// Total in the middle
+	sb.WriteString(fmt.Sprintf(`<text x="%d" y="%s" text-anchor="middle" font-family="system-ui, sans-serif" font-size="20" font-weight="600" fill="#333">%s</text>`,
+		cx, coord(cy+2), badge.FormatLOC(int64(total))))
+	sb.WriteString("\n")
+	sb.WriteString(fmt.Sprintf(`<text x="%d" y="%s" text-anchor="middle" font-family="system-ui, sans-serif" font-size="11" fill="#666">lines of code</text>`, cx, coord(cy+18)))
+	sb.WriteString("\n")
+
+	// Legend, vertically centered on the ring
+	y := cy - float64(len(shares)*rowH)/2
+	for _, sh := range shares {
+		sb.WriteString(fmt.Sprintf(`<rect x="%d" y="%s" width="12" height="12" rx="2" fill="%s"/>`, legendX, coord(y+4), sh.Color))
+		sb.WriteString(fmt.Sprintf(`<text x="%d" y="%s" font-family="system-ui, sans-serif" font-size="13" fill="#333">%s</text>`, legendX+20, coord(y+15), html.EscapeString(sh.Language)))
+		sb.WriteString(fmt.Sprintf(`<text x="%d" y="%s" text-anchor="end" font-family="system-ui, sans-serif" font-size="13" fill="#666">%s</text>`, width-20, coord(y+15), formatShare(sh.Fraction)))
+		sb.WriteString("\n")
+		y += rowH
+	}
+
+	sb.WriteString("</svg>")
+	return []byte(sb.String())
+}
+
+// RenderLanguageBar renders the language composition of a snapshot, by lines
+// of code, as a horizontal stacked bar with a legend beneath, in the style of
+// GitHub's repository language bar.
+func RenderLanguageBar(snapshot store.Snapshot, opt Options) []byte {
+	shares := languageShares(snapshot, opt)
+
+	const (
+		width   = 800
+		margin  = 20
+		barY    = 40
+		barH    = 12
+		rowH    = 22
+		charW   = 7.0 // rough width of a 13px character
+		itemGap = 24
+	)
+	if len(shares) == 0 {
+		return []byte(emptySVG(width, 100))
+	}
+
+	// Lay out the legend first, wrapping items onto new rows, to size the image
+	type item struct {
+		share
+		x, y float64
+	}
+	var items []item
+	x, y := float64(margin), float64(barY+barH+28)
+	for _, sh := range shares {
+		w := 16 + charW*float64(len(sh.Language)+1+len(formatShare(sh.Fraction)))
+		if x > margin && x+w > width-margin {
+			x, y = margin, y+rowH
+		}
+		items = append(items, item{share: sh, x: x, y: y})
+		x += w + itemGap
+	}
+	height := int(y) + 14
+
+	var sb strings.Builder
+	sb.WriteString(svgOpen(width, height, "Languages", sharesSummary(shares)))
+	sb.WriteString("\n")
+	sb.WriteString(fmt.Sprintf(`<rect width="%d" height="%d" fill="white"/>`, width, height))
+	sb.WriteString("\n")
+	sb.WriteString(fmt.Sprintf(`<defs><clipPath id="barClip"><rect x="%d" y="%d" width="%d" height="%d" rx="%d"/></clipPath></defs>`, margin, barY, width-2*margin, barH, barH/2))
+	sb.WriteString("\n")
+	sb.WriteString(fmt.Sprintf(`<text x="%d" y="24" font-family="system-ui, sans-serif" font-size="16" font-weight="600" fill="#333">Languages</text>`, margin))
+	sb.WriteString("\n")
+
+	// Segments, with a hairline gap between neighbours as on GitHub
+	sb.WriteString(`<g clip-path="url(#barClip)">`)
+	left := float64(margin)
+	for i, sh := range shares {
+		w := sh.Fraction * (width - 2*margin)
+		if i < len(shares)-1 {
+			w = math.Max(w-1, 0)
+		}
+		sb.WriteString(fmt.Sprintf(`<rect x="%s" y="%d" width="%s" height="%d" fill="%s">%s</rect>`, coord(left), barY, coord(w), barH, sh.Color, shareTitle(sh)))
+		left += sh.Fraction * (width - 2*margin)
+	}
+	sb.WriteString("</g>\n")
+
+	for _, it := range items {
+		sb.WriteString(fmt.Sprintf(`<circle cx="%s" cy="%s" r="5" fill="%s"/>`, coord(it.x+5), coord(it.y-4), it.Color))
+		sb.WriteString(fmt.Sprintf(`<text x="%s" y="%s" font-family="system-ui, sans-serif" font-size="13"><tspan font-weight="600" fill="#333">%s</tspan> <tspan fill="#666">%s</tspan></text>`,
+			coord(it.x+16), coord(it.y), html.EscapeString(it.Language), formatShare(it.Fraction)))
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("</svg>")
+	return []byte(sb.String())
+}
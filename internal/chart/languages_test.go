@@ -0,0 +1,93 @@
+package chart
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rjwalters/ghloc/internal/golden"
+	"github.com/rjwalters/ghloc/internal/store"
+)
+
+var shareSnapshot = store.Snapshot{
+	TotalLOC: 12403,
+	Languages: []store.LanguageRecord{
+		{Language: "Markdown", Code: 1500},
+		{Language: "Go", Code: 9000},
+		{Language: "Shell", Code: 60},
+		{Language: "YAML", Code: 800},
+		{Language: "JSON", Code: 40},
+		{Language: "C++", Code: 1000},
+		{Language: "Plain Text", Code: 3},
+		{Language: "License", Code: 0},
+	},
+}
+
+func TestLanguageShares(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  Options
+		want []string
+	}{
+		{"default threshold", Options{}, []string{"Go", "Markdown", "C++", "YAML", "Other"}},
+		{"no threshold", Options{MinShare: -1}, []string{"Go", "Markdown", "C++", "YAML", "Shell", "JSON", "Plain Text"}},
+		{"max languages", Options{MaxLanguages: 2}, []string{"Go", "Markdown", "Other"}},
+		{"single small language keeps its name", Options{MinShare: 0.0005}, []string{"Go", "Markdown", "C++", "YAML", "Shell", "JSON", "Plain Text"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shares := languageShares(shareSnapshot, tt.opt)
+			var got []string
+			var sum float64
+			for _, sh := range shares {
+				got = append(got, sh.Language)
+				sum += sh.Fraction
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("languages = %v, want %v", got, tt.want)
+			}
+			if sum < 0.9999 || sum > 1.0001 {
+				t.Errorf("fractions sum to %v, want 1", sum)
+			}
+		})
+	}
+
+	if shares := languageShares(shareSnapshot, Options{}); shares[len(shares)-1].Code != 103 {
+		t.Errorf("Other = %d lines, want 103", shares[len(shares)-1].Code)
+	}
+}
+
+func TestFormatShare(t *testing.T) {
+	for f, want := range map[float64]string{0.72345: "72.3%", 1: "100.0%", 0.0004: "<0.1%", 0: "0.0%"} {
+		if got := formatShare(f); got != want {
+			t.Errorf("formatShare(%v) = %q, want %q", f, got, want)
+		}
+	}
+}
+
+func TestRenderLanguageDonut_Golden(t *testing.T) {
+	golden.Check(t, "languages-share.svg", RenderLanguageDonut(shareSnapshot, Options{}))
+}
+
+func TestRenderLanguageBar_Golden(t *testing.T) {
+	golden.Check(t, "languages-bar.svg", RenderLanguageBar(shareSnapshot, Options{}))
+}
+
+func TestRenderLanguageCharts_Empty(t *testing.T) {
+	empty := store.Snapshot{Languages: []store.LanguageRecord{{Language: "Go"}}}
+	for name, svg := range map[string][]byte{"donut": RenderLanguageDonut(empty, Options{}), "bar": RenderLanguageBar(empty, Options{})} {
+		if !strings.Contains(string(svg), "No data yet") {
+			t.Errorf("%s: expected placeholder for a snapshot without code", name)
+		}
+	}
+}
+
+func TestRenderLanguageBar_Wraps(t *testing.T) {
+	var s store.Snapshot
+	for _, name := range []string{"JavaScript", "TypeScript", "Python", "Objective-C++", "Visual Basic for Applications", "Jupyter Notebook", "Dockerfile", "CoffeeScript"} {
+		s.Languages = append(s.Languages, store.LanguageRecord{Language: name, Code: 100})
+	}
+	svg := string(RenderLanguageBar(s, Options{}))
+	if !strings.Contains(svg, `height="116"`) {
+		t.Errorf("expected the legend to wrap onto a second row:\n%s", svg)
+	}
+}
@@ -2,17 +2,77 @@ package chart
 
 import (
 	"fmt"
+	"html"
 	"math"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/rjwalters/ghloc/internal/metric"
 	"github.com/rjwalters/ghloc/internal/store"
 )
 
+// Options controls optional chart rendering behavior.
+type Options struct {
+	// GapThreshold breaks the line wherever consecutive snapshots are further
+	// apart than this duration instead of interpolating across the gap.
+	// Zero disables gap detection.
+	GapThreshold time.Duration
+
+	// MinShare folds languages with less than this fraction of the code into
+	// "Other" in language share charts. Zero means DefaultMinShare.
+	MinShare float64
+
+	// MaxLanguages caps the languages shown individually in share charts,
+	// folding the rest into "Other". Zero means DefaultMaxLanguages.
+	MaxLanguages int
+
+	// GrowthPeriod sets the bars of growth charts to weeks or months.
+	// Empty means Monthly.
+	GrowthPeriod Period
+
+	// RollingAverage overlays growth charts with the mean change over this
+	// many periods. Values below 2 disable the overlay.
+	RollingAverage int
+}
+
+// Defaults for Options.MinShare and Options.MaxLanguages.
+const (
+	DefaultMinShare     = 0.01
+	DefaultMaxLanguages = 8
+)
+
 // RenderHistoryChart generates a star-history-style SVG line chart showing LOC over time.
-func RenderHistoryChart(snapshots []store.Snapshot) []byte {
-	if len(snapshots) == 0 {
-		return []byte(emptySVG())
+// Snapshots may be in any order; they are sorted by time and snapshots sharing a
+// timestamp are collapsed to the last one recorded.
+func RenderHistoryChart(snapshots []store.Snapshot, opt Options) []byte {
+	loc, _ := metric.Lookup("loc")
+	return RenderMetricChart(snapshots, loc, opt)
+}
+
+// RenderMetricChart generates a line chart of an arbitrary metric over time.
+// Snapshots for which the metric has no value are left out.
+func RenderMetricChart(snapshots []store.Snapshot, m metric.Metric, opt Options) []byte {
+	// Extract data
+	var times []time.Time
+	var values []float64
+	var minVal, maxVal float64
+	for _, s := range normalizeSnapshots(snapshots) {
+		v, ok := m.Value(s)
+		if !ok {
+			continue
+		}
+		if len(values) == 0 || v < minVal {
+			minVal = v
+		}
+		if len(values) == 0 || v > maxVal {
+			maxVal = v
+		}
+		times = append(times, s.CreatedAt)
+		values = append(values, v)
+	}
+	if len(values) == 0 {
+		return []byte(emptySVG(800, 400))
 	}
 
 	// Chart dimensions
@@ -27,21 +87,6 @@ func RenderHistoryChart(snapshots []store.Snapshot) []byte {
 		plotH       = height - marginTop - marginBot
 	)
 
-	// Extract data
-	times := make([]time.Time, len(snapshots))
-	values := make([]float64, len(snapshots))
-	var minVal, maxVal float64
-	for i, s := range snapshots {
-		times[i] = s.CreatedAt
-		values[i] = float64(s.TotalLOC)
-		if i == 0 || values[i] < minVal {
-			minVal = values[i]
-		}
-		if i == 0 || values[i] > maxVal {
-			maxVal = values[i]
-		}
-	}
-
 	// Add 10% padding to Y axis
 	yRange := maxVal - minVal
 	if yRange == 0 {
@@ -62,16 +107,16 @@ func RenderHistoryChart(snapshots []store.Snapshot) []byte {
 	}
 
 	// Map data to pixel coordinates
-	xCoords := make([]float64, len(snapshots))
-	yCoords := make([]float64, len(snapshots))
-	for i := range snapshots {
+	xCoords := make([]float64, len(values))
+	yCoords := make([]float64, len(values))
+	for i := range values {
 		xCoords[i] = marginLeft + (times[i].Sub(tMin).Seconds()/tRange)*plotW
 		yCoords[i] = marginTop + plotH - ((values[i]-yMin)/(yMax-yMin))*plotH
 	}
 
 	// Build SVG
 	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="%d" height="%d">`, width, height, width, height))
+	sb.WriteString(svgOpen(width, height, m.Title, trendSummary(m.Title, m.Format, values[0], values[len(values)-1], tMin, tMax)))
 	sb.WriteString("\n")
 
 	// Background
@@ -91,9 +136,9 @@ func RenderHistoryChart(snapshots []store.Snapshot) []byte {
 	yTicks := niceAxisTicks(yMin, yMax, 5)
 	for _, tick := range yTicks {
 		y := marginTop + plotH - ((tick-yMin)/(yMax-yMin))*plotH
-		sb.WriteString(fmt.Sprintf(`<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#E5E5E5" stroke-width="1"/>`, marginLeft, y, width-marginRight, y))
+		sb.WriteString(fmt.Sprintf(`<line x1="%d" y1="%s" x2="%d" y2="%s" stroke="#E5E5E5" stroke-width="1"/>`, marginLeft, coord(y), width-marginRight, coord(y)))
 		sb.WriteString("\n")
-		sb.WriteString(fmt.Sprintf(`<text x="%d" y="%.1f" text-anchor="end" font-family="system-ui, sans-serif" font-size="12" fill="#666">%s</text>`, marginLeft-8, y+4, formatAxisValue(tick)))
+		sb.WriteString(fmt.Sprintf(`<text x="%d" y="%s" text-anchor="end" font-family="system-ui, sans-serif" font-size="12" fill="#666">%s</text>`, marginLeft-8, coord(y+4), formatAxisValue(tick)))
 		sb.WriteString("\n")
 	}
 
@@ -101,29 +146,40 @@ func RenderHistoryChart(snapshots []store.Snapshot) []byte {
 	xTicks := dateAxisTicks(tMin, tMax, 6)
 	for _, t := range xTicks {
 		x := marginLeft + (t.Sub(tMin).Seconds()/tRange)*plotW
-		sb.WriteString(fmt.Sprintf(`<text x="%.1f" y="%d" text-anchor="middle" font-family="system-ui, sans-serif" font-size="12" fill="#666">%s</text>`, x, height-marginBot+20, t.Format("Jan 2006")))
+		sb.WriteString(fmt.Sprintf(`<text x="%s" y="%d" text-anchor="middle" font-family="system-ui, sans-serif" font-size="12" fill="#666">%s</text>`, coord(x), height-marginBot+20, t.Format("Jan 2006")))
 		sb.WriteString("\n")
 	}
 
-	// Area fill path
-	if len(xCoords) > 1 {
+	segments := splitAtGaps(times, opt.GapThreshold)
+
+	// Area fill path, one closed shape per segment
+	for _, seg := range segments {
+		if seg[1]-seg[0] < 2 {
+			continue
+		}
+		first, last := seg[0], seg[1]-1
 		sb.WriteString(`<path d="`)
-		sb.WriteString(fmt.Sprintf("M%.1f,%.1f", xCoords[0], float64(marginTop+plotH)))
-		for i := range xCoords {
-			sb.WriteString(fmt.Sprintf(" L%.1f,%.1f", xCoords[i], yCoords[i]))
+		sb.WriteString(fmt.Sprintf("M%s,%s", coord(xCoords[first]), coord(float64(marginTop+plotH))))
+		for i := first; i <= last; i++ {
+			sb.WriteString(fmt.Sprintf(" L%s,%s", coord(xCoords[i]), coord(yCoords[i])))
 		}
-		sb.WriteString(fmt.Sprintf(" L%.1f,%.1f", xCoords[len(xCoords)-1], float64(marginTop+plotH)))
+		sb.WriteString(fmt.Sprintf(" L%s,%s", coord(xCoords[last]), coord(float64(marginTop+plotH))))
 		sb.WriteString(`Z" fill="url(#areaGrad)"/>`)
 		sb.WriteString("\n")
 	}
 
-	// Line path
+	// Line path, restarting with a move command at each segment
 	sb.WriteString(`<path d="`)
-	for i := range xCoords {
-		if i == 0 {
-			sb.WriteString(fmt.Sprintf("M%.1f,%.1f", xCoords[i], yCoords[i]))
-		} else {
-			sb.WriteString(fmt.Sprintf(" L%.1f,%.1f", xCoords[i], yCoords[i]))
+	for _, seg := range segments {
+		for i := seg[0]; i < seg[1]; i++ {
+			if i == seg[0] {
+				if i > 0 {
+					sb.WriteString(" ")
+				}
+				sb.WriteString(fmt.Sprintf("M%s,%s", coord(xCoords[i]), coord(yCoords[i])))
+			} else {
+				sb.WriteString(fmt.Sprintf(" L%s,%s", coord(xCoords[i]), coord(yCoords[i])))
+			}
 		}
 	}
 	sb.WriteString(`" fill="none" stroke="#4A90D9" stroke-width="2.5" stroke-linejoin="round" stroke-linecap="round"/>`)
@@ -131,12 +187,13 @@ func RenderHistoryChart(snapshots []store.Snapshot) []byte {
 
 	// Data points
 	for i := range xCoords {
-		sb.WriteString(fmt.Sprintf(`<circle cx="%.1f" cy="%.1f" r="3.5" fill="white" stroke="#4A90D9" stroke-width="2"/>`, xCoords[i], yCoords[i]))
+		sb.WriteString(fmt.Sprintf(`<circle cx="%s" cy="%s" r="3.5" fill="white" stroke="#4A90D9" stroke-width="2"><title>%s: %s</title></circle>`,
+			coord(xCoords[i]), coord(yCoords[i]), times[i].Format(time.DateOnly), html.EscapeString(m.Format(values[i]))))
 		sb.WriteString("\n")
 	}
 
 	// Title
-	sb.WriteString(fmt.Sprintf(`<text x="%d" y="24" font-family="system-ui, sans-serif" font-size="16" font-weight="600" fill="#333">Lines of Code</text>`, marginLeft))
+	sb.WriteString(fmt.Sprintf(`<text x="%d" y="24" font-family="system-ui, sans-serif" font-size="16" font-weight="600" fill="#333">%s</text>`, marginLeft, html.EscapeString(m.Title)))
 	sb.WriteString("\n")
 
 	// Axes
@@ -147,6 +204,27 @@ func RenderHistoryChart(snapshots []store.Snapshot) []byte {
 	return []byte(sb.String())
 }
 
+// normalizeSnapshots returns a copy of snapshots sorted by CreatedAt, keeping only
+// the last snapshot recorded for any given timestamp, as history merges do.
+func normalizeSnapshots(snapshots []store.Snapshot) []store.Snapshot {
+	return store.Normalize(snapshots)
+}
+
+// splitAtGaps partitions sorted times into half-open [start, end) index ranges,
+// starting a new range wherever consecutive times are more than threshold apart.
+// A zero threshold yields a single range.
+func splitAtGaps(times []time.Time, threshold time.Duration) [][2]int {
+	var segments [][2]int
+	start := 0
+	for i := 1; i < len(times); i++ {
+		if threshold > 0 && times[i].Sub(times[i-1]) > threshold {
+			segments = append(segments, [2]int{start, i})
+			start = i
+		}
+	}
+	return append(segments, [2]int{start, len(times)})
+}
+
 // niceAxisTicks generates clean tick values for a numeric axis.
 func niceAxisTicks(min, max float64, count int) []float64 {
 	rawStep := (max - min) / float64(count)
@@ -188,6 +266,16 @@ func dateAxisTicks(min, max time.Time, count int) []time.Time {
 	return ticks
 }
 
+// coord formats a pixel coordinate with one decimal, the same way on every
+// platform and without a "-0.0" for values that round to zero.
+func coord(v float64) string {
+	v = math.Round(v*10) / 10
+	if v == 0 {
+		v = 0 // drop the sign of negative zero
+	}
+	return strconv.FormatFloat(v, 'f', 1, 64)
+}
+
 // formatAxisValue formats a number for axis labels.
 func formatAxisValue(v float64) string {
 	switch {
@@ -195,14 +283,42 @@ func formatAxisValue(v float64) string {
 		return fmt.Sprintf("%.1fM", v/1_000_000)
 	case v >= 1_000:
 		return fmt.Sprintf("%.0fk", v/1_000)
-	default:
+	case v >= 10 || v == math.Trunc(v):
 		return fmt.Sprintf("%.0f", v)
+	default:
+		return strconv.FormatFloat(v, 'g', 3, 64)
+	}
+}
+
+// svgOpen returns the root element of a chart with the accessibility metadata
+// screen readers use: an image role labelled by the title, and a <title> and
+// <desc> carrying the title and a text summary of the data.
+func svgOpen(width, height int, title, desc string) string {
+	title, desc = html.EscapeString(title), html.EscapeString(desc)
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="%d" height="%d" role="img" aria-label="%s"><title>%s</title><desc>%s</desc>`,
+		width, height, width, height, title, title, desc)
+}
+
+// trendSummary describes how a value changed between two times, e.g. "Lines
+// of Code grew from 969 to 12.4k between Jan 2024 and Oct 2026".
+func trendSummary(label string, format func(float64) string, first, last float64, from, to time.Time) string {
+	const layout = "Jan 2006"
+	if from.Format(layout) == to.Format(layout) {
+		return fmt.Sprintf("%s was %s in %s", label, format(last), to.Format(layout))
+	}
+	verb := "grew"
+	switch {
+	case last < first:
+		verb = "fell"
+	case format(last) == format(first):
+		return fmt.Sprintf("%s stayed at %s between %s and %s", label, format(last), from.Format(layout), to.Format(layout))
 	}
+	return fmt.Sprintf("%s %s from %s to %s between %s and %s", label, verb, format(first), format(last), from.Format(layout), to.Format(layout))
 }
 
-func emptySVG() string {
-	return `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 800 400" width="800" height="400">
-<rect width="800" height="400" fill="white"/>
-<text x="400" y="200" text-anchor="middle" font-family="system-ui, sans-serif" font-size="16" fill="#999">No data yet</text>
-</svg>`
+func emptySVG(width, height int) string {
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="%d" height="%d" role="img" aria-label="No data yet"><title>No data yet</title>
+<rect width="%d" height="%d" fill="white"/>
+<text x="%d" y="%d" text-anchor="middle" font-family="system-ui, sans-serif" font-size="16" fill="#999">No data yet</text>
+</svg>`, width, height, width, height, width, height, width/2, height/2)
 }
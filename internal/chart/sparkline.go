@@ -0,0 +1,130 @@
+package chart
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rjwalters/ghloc/internal/badge"
+	"github.com/rjwalters/ghloc/internal/store"
+)
+
+// Sparkline dimensions, small enough to sit in a table cell.
+const (
+	SparklineWidth  = 120
+	SparklineHeight = 20
+)
+
+// RenderSparkline draws lines of code over the last n snapshots (all of them
+// when n <= 0) as a bare trend line, without axes or labels.
+func RenderSparkline(snapshots []store.Snapshot, n int) []byte {
+	window := lastSnapshots(snapshots, n)
+	var sb strings.Builder
+	sb.WriteString(svgOpen(SparklineWidth, SparklineHeight, "Lines of code trend", sparklineSummary(window)))
+	sb.WriteString(sparkline(window, 0, 0, SparklineWidth, SparklineHeight))
+	sb.WriteString("</svg>")
+	return []byte(sb.String())
+}
+
+// RenderSparklineBadge renders the lines of code badge for the latest snapshot
+// with a sparkline of the last n snapshots beside it.
+func RenderSparklineBadge(snapshots []store.Snapshot, n int) []byte {
+	window := lastSnapshots(snapshots, n)
+	var loc int64
+	if len(window) > 0 {
+		loc = window[len(window)-1].TotalLOC
+	}
+	badgeSVG := badge.RenderSVG(badge.FormatLOC(loc))
+	badgeW := svgWidth(badgeSVG)
+
+	const pad = 4
+	width := badgeW + SparklineWidth + 2*pad
+	var sb strings.Builder
+	sb.WriteString(svgOpen(width, SparklineHeight, "lines of code: "+badge.FormatLOC(loc), sparklineSummary(window)))
+	// A light panel behind the trend, tucked under the badge's rounded end
+	sb.WriteString(fmt.Sprintf(`<rect x="%d" y="0.5" width="%d" height="%d" rx="3" fill="#F6F8FA" stroke="#E1E4E8"/>`,
+		badgeW-4, SparklineWidth+2*pad+3, SparklineHeight-1))
+	sb.Write(badgeSVG)
+	sb.WriteString(sparkline(window, float64(badgeW+pad), 2, SparklineWidth, SparklineHeight-4))
+	sb.WriteString("</svg>")
+	return []byte(sb.String())
+}
+
+// sparklineSummary describes the trend drawn by a sparkline.
+func sparklineSummary(window []store.Snapshot) string {
+	if len(window) == 0 {
+		return "No data yet"
+	}
+	first, last := window[0], window[len(window)-1]
+	return trendSummary("Lines of code", func(v float64) string { return badge.FormatLOC(int64(v)) },
+		float64(first.TotalLOC), float64(last.TotalLOC), first.CreatedAt, last.CreatedAt)
+}
+
+// lastSnapshots returns the last n snapshots in time order, or all of them
+// when n <= 0.
+func lastSnapshots(snapshots []store.Snapshot, n int) []store.Snapshot {
+	sorted := normalizeSnapshots(snapshots)
+	if n > 0 && len(sorted) > n {
+		sorted = sorted[len(sorted)-n:]
+	}
+	return sorted
+}
+
+// sparkline returns a path tracing total LOC across snapshots within the box
+// at (x, y) of size w by h, with a dot on the latest value.
+func sparkline(snapshots []store.Snapshot, x, y, w, h float64) string {
+	if len(snapshots) == 0 {
+		return ""
+	}
+	const inset = 2 // keep the stroke and dot inside the box
+	minVal, maxVal := float64(snapshots[0].TotalLOC), float64(snapshots[0].TotalLOC)
+	for _, s := range snapshots {
+		minVal = min(minVal, float64(s.TotalLOC))
+		maxVal = max(maxVal, float64(s.TotalLOC))
+	}
+	tMin, tMax := snapshots[0].CreatedAt, snapshots[len(snapshots)-1].CreatedAt
+
+	point := func(s store.Snapshot) (float64, float64) {
+		px := x + w - inset // a lone snapshot sits at the right edge
+		if span := tMax.Sub(tMin); span > 0 {
+			px = x + inset + float64(s.CreatedAt.Sub(tMin))/float64(span)*(w-2*inset)
+		}
+		py := y + h/2
+		if maxVal > minVal {
+			py = y + h - inset - (float64(s.TotalLOC)-minVal)/(maxVal-minVal)*(h-2*inset)
+		}
+		return px, py
+	}
+
+	var d []string
+	for _, s := range snapshots {
+		px, py := point(s)
+		if len(d) == 0 {
+			if len(snapshots) == 1 {
+				d = append(d, "M"+coord(x+inset)+","+coord(py)) // a flat line
+			} else {
+				d = append(d, "M"+coord(px)+","+coord(py))
+				continue
+			}
+		}
+		d = append(d, "L"+coord(px)+","+coord(py))
+	}
+	px, py := point(snapshots[len(snapshots)-1])
+	return fmt.Sprintf(`<path d="%s" fill="none" stroke="#4A90D9" stroke-width="1.5" stroke-linejoin="round" stroke-linecap="round"/>`, strings.Join(d, " ")) +
+		fmt.Sprintf(`<circle cx="%s" cy="%s" r="2" fill="#4A90D9"><title>%s: %s</title></circle>`,
+			coord(px), coord(py), snapshots[len(snapshots)-1].CreatedAt.Format(time.DateOnly), badge.FormatLOC(snapshots[len(snapshots)-1].TotalLOC))
+}
+
+var svgWidthRe = regexp.MustCompile(`<svg[^>]*\swidth="(\d+)"`)
+
+// svgWidth returns the pixel width declared by an SVG document.
+func svgWidth(svg []byte) int {
+	m := svgWidthRe.FindSubmatch(svg)
+	if m == nil {
+		return 0
+	}
+	w, _ := strconv.Atoi(string(m[1]))
+	return w
+}
@@ -0,0 +1,52 @@
+package chart
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rjwalters/ghloc/internal/golden"
+	"github.com/rjwalters/ghloc/internal/store"
+)
+
+func TestRenderSparkline_Golden(t *testing.T) {
+	golden.Check(t, "sparkline.svg", RenderSparkline(testHistory(), 0))
+}
+
+func TestRenderSparklineBadge_Golden(t *testing.T) {
+	golden.Check(t, "badge-sparkline.svg", RenderSparklineBadge(testHistory(), 0))
+}
+
+func TestRenderSparkline_LastN(t *testing.T) {
+	svg := string(RenderSparkline(testHistory(), 3))
+	if got := strings.Count(svg, " L"); got != 2 {
+		t.Errorf("expected 3 points (2 line segments), got %d segments:\n%s", got, svg)
+	}
+	if !strings.Contains(svg, `width="120" height="20"`) {
+		t.Errorf("unexpected size:\n%s", svg)
+	}
+	// The window's own range fills the height: latest (max) at the top
+	if !strings.Contains(svg, `<circle cx="118.0" cy="2.0"`) {
+		t.Errorf("latest point should be at the top right:\n%s", svg)
+	}
+}
+
+func TestRenderSparkline_FewSnapshots(t *testing.T) {
+	if svg := string(RenderSparkline(nil, 10)); strings.Contains(svg, "<path") {
+		t.Errorf("expected an empty sparkline without history:\n%s", svg)
+	}
+	one := []store.Snapshot{{TotalLOC: 42, CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}}
+	if svg := string(RenderSparkline(one, 10)); !strings.Contains(svg, `d="M2.0,10.0 L118.0,10.0"`) {
+		t.Errorf("expected a flat line for a single snapshot:\n%s", svg)
+	}
+}
+
+func TestRenderSparklineBadge(t *testing.T) {
+	svg := string(RenderSparklineBadge(testHistory(), 5))
+	if !strings.Contains(svg, ">12.4k</text>") {
+		t.Errorf("badge should show the latest count:\n%s", svg)
+	}
+	if w := svgWidth([]byte(svg)); w != 128+SparklineWidth+8 {
+		t.Errorf("width = %d, want badge plus sparkline", w)
+	}
+}
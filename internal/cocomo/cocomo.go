@@ -0,0 +1,87 @@
+package cocomo
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/rjwalters/ghloc/internal/counter"
+)
+
+// ProjectType selects the Basic COCOMO coefficients used for an estimate.
+type ProjectType string
+
+const (
+	// Organic projects have small, experienced teams working on familiar problems.
+	Organic ProjectType = "organic"
+	// SemiDetached projects sit between organic and embedded in team size and experience.
+	SemiDetached ProjectType = "semi-detached"
+	// Embedded projects have tight constraints and need the largest, most experienced teams.
+	Embedded ProjectType = "embedded"
+)
+
+// coefficients holds Boehm's Basic COCOMO a, b, c and d values, matching scc.
+var coefficients = map[ProjectType][4]float64{
+	Organic:      {2.4, 1.05, 2.5, 0.38},
+	SemiDetached: {3.0, 1.12, 2.5, 0.35},
+	Embedded:     {3.6, 1.20, 2.5, 0.32},
+}
+
+// Params configures a COCOMO estimate.
+type Params struct {
+	AverageWage float64 // yearly salary in dollars
+	Overhead    float64 // multiplier applied to salary cost
+	EAF         float64 // effort adjustment factor
+	ProjectType ProjectType
+}
+
+// DefaultParams returns the same defaults scc uses.
+func DefaultParams() Params {
+	return Params{
+		AverageWage: 56286,
+		Overhead:    2.4,
+		EAF:         1.0,
+		ProjectType: Organic,
+	}
+}
+
+// Estimate is the result of a Basic COCOMO calculation.
+type Estimate struct {
+	Cost           float64 // dollars
+	EffortMonths   float64 // person-months
+	ScheduleMonths float64 // calendar months
+	People         float64 // effort divided by schedule
+}
+
+// ParseProjectType validates a project type name.
+func ParseProjectType(s string) (ProjectType, error) {
+	t := ProjectType(s)
+	if _, ok := coefficients[t]; !ok {
+		return "", fmt.Errorf("unknown COCOMO project type %q (want organic, semi-detached or embedded)", s)
+	}
+	return t, nil
+}
+
+// Compute estimates cost, effort and schedule for sloc source lines of code.
+func Compute(sloc int64, p Params) (Estimate, error) {
+	c, ok := coefficients[p.ProjectType]
+	if !ok {
+		return Estimate{}, fmt.Errorf("unknown COCOMO project type %q", p.ProjectType)
+	}
+	if sloc <= 0 {
+		return Estimate{}, nil
+	}
+
+	effort := c[0] * math.Pow(float64(sloc)/1000, c[1]) * p.EAF
+	schedule := c[2] * math.Pow(effort, c[3])
+	return Estimate{
+		Cost:           effort * (p.AverageWage / 12) * p.Overhead,
+		EffortMonths:   effort,
+		ScheduleMonths: schedule,
+		People:         effort / schedule,
+	}, nil
+}
+
+// FromResult estimates the counted code in result.
+func FromResult(result *counter.LOCResult, p Params) (Estimate, error) {
+	return Compute(result.TotalCode, p)
+}
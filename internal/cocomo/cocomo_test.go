@@ -0,0 +1,91 @@
+package cocomo
+
+import (
+	"math"
+	"testing"
+
+	"github.com/rjwalters/ghloc/internal/counter"
+)
+
+func TestCompute_Organic(t *testing.T) {
+	est, err := Compute(10_000, DefaultParams())
+	if err != nil {
+		t.Fatalf("Compute() error: %v", err)
+	}
+
+	// 2.4 * 10^1.05 person-months
+	wantEffort := 2.4 * math.Pow(10, 1.05)
+	if math.Abs(est.EffortMonths-wantEffort) > 1e-9 {
+		t.Errorf("EffortMonths: got %v, want %v", est.EffortMonths, wantEffort)
+	}
+	wantSchedule := 2.5 * math.Pow(wantEffort, 0.38)
+	if math.Abs(est.ScheduleMonths-wantSchedule) > 1e-9 {
+		t.Errorf("ScheduleMonths: got %v, want %v", est.ScheduleMonths, wantSchedule)
+	}
+	wantCost := wantEffort * (56286.0 / 12) * 2.4
+	if math.Abs(est.Cost-wantCost) > 1e-6 {
+		t.Errorf("Cost: got %v, want %v", est.Cost, wantCost)
+	}
+	if math.Abs(est.People-wantEffort/wantSchedule) > 1e-9 {
+		t.Errorf("People: got %v, want %v", est.People, wantEffort/wantSchedule)
+	}
+}
+
+func TestCompute_ProjectTypesIncreaseEffort(t *testing.T) {
+	var prev float64
+	for _, pt := range []ProjectType{Organic, SemiDetached, Embedded} {
+		p := DefaultParams()
+		p.ProjectType = pt
+		est, err := Compute(50_000, p)
+		if err != nil {
+			t.Fatalf("Compute(%s) error: %v", pt, err)
+		}
+		if est.EffortMonths <= prev {
+			t.Errorf("%s effort %v should exceed previous %v", pt, est.EffortMonths, prev)
+		}
+		prev = est.EffortMonths
+	}
+}
+
+func TestCompute_WageAndOverheadScaleCost(t *testing.T) {
+	base, _ := Compute(10_000, DefaultParams())
+
+	p := DefaultParams()
+	p.AverageWage *= 2
+	p.Overhead = 1.2
+	scaled, _ := Compute(10_000, p)
+
+	if math.Abs(scaled.Cost-base.Cost) > 1e-6 {
+		t.Errorf("doubling wage and halving overhead should keep cost: got %v, want %v", scaled.Cost, base.Cost)
+	}
+}
+
+func TestCompute_Zero(t *testing.T) {
+	est, err := Compute(0, DefaultParams())
+	if err != nil {
+		t.Fatalf("Compute() error: %v", err)
+	}
+	if est != (Estimate{}) {
+		t.Errorf("expected zero estimate, got %+v", est)
+	}
+}
+
+func TestParseProjectType(t *testing.T) {
+	if pt, err := ParseProjectType("semi-detached"); err != nil || pt != SemiDetached {
+		t.Errorf("ParseProjectType(semi-detached) = %q, %v", pt, err)
+	}
+	if _, err := ParseProjectType("agile"); err == nil {
+		t.Error("expected error for unknown project type")
+	}
+}
+
+func TestFromResult(t *testing.T) {
+	est, err := FromResult(&counter.LOCResult{TotalCode: 10_000}, DefaultParams())
+	if err != nil {
+		t.Fatalf("FromResult() error: %v", err)
+	}
+	want, _ := Compute(10_000, DefaultParams())
+	if est != want {
+		t.Errorf("FromResult() = %+v, want %+v", est, want)
+	}
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/rjwalters/ghloc/internal/badge"
+	"github.com/rjwalters/ghloc/internal/counter"
+)
+
+// Config holds user settings read from .ghloc/config.json.
+type Config struct {
+	// TestPatterns overrides the default globs used to classify test files.
+	TestPatterns []string  `json:"test_patterns,omitempty"`
+	Languages    Languages `json:"languages"`
+
+	FollowSymlinks bool   `json:"follow_symlinks,omitempty"`
+	Submodules     string `json:"submodules,omitempty"` // include, exclude or separate
+
+	MaxFileSize          int64 `json:"max_file_size,omitempty"`           // bytes; negative disables
+	MaxAverageLineLength int   `json:"max_average_line_length,omitempty"` // bytes; negative disables
+
+	// DocCoverage grades the documentation coverage badge by the minimum
+	// comment density, in percent, that earns each color and label.
+	DocCoverage badge.Thresholds `json:"doc_coverage_thresholds,omitempty"`
+}
+
+// Languages configures how detected languages are renamed, grouped and excluded.
+type Languages struct {
+	// Overrides maps path globs to the language files matching them must use.
+	Overrides map[string]string   `json:"overrides,omitempty"`
+	Rename    map[string]string   `json:"rename,omitempty"`
+	Groups    map[string][]string `json:"groups,omitempty"`
+	Exclude   []string            `json:"exclude,omitempty"`
+	CodeOnly  bool                `json:"code_only,omitempty"`
+}
+
+// Load reads a Config from a JSON file.
+// Returns an empty Config if the file does not exist.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Config{}, nil
+		}
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+func (c *Config) validate() error {
+	member := make(map[string]string)
+	for group, languages := range c.Languages.Groups {
+		for _, lang := range languages {
+			if other, ok := member[lang]; ok {
+				return fmt.Errorf("language %q is in both groups %q and %q", lang, other, group)
+			}
+			member[lang] = group
+		}
+	}
+	if _, err := counter.ParseSubmoduleMode(c.Submodules); err != nil {
+		return err
+	}
+	if err := c.DocCoverage.Validate(); err != nil {
+		return fmt.Errorf("doc_coverage_thresholds: %w", err)
+	}
+	return nil
+}
+
+// DocThresholds returns the documentation coverage badge grading, falling
+// back to badge.DefaultDocThresholds.
+func (c *Config) DocThresholds() badge.Thresholds {
+	if len(c.DocCoverage) == 0 {
+		return badge.DefaultDocThresholds
+	}
+	return c.DocCoverage
+}
+
+// CounterOptions converts the config into options for counter.Count.
+func (c *Config) CounterOptions() counter.Options {
+	submodules, _ := counter.ParseSubmoduleMode(c.Submodules) // checked by validate
+	return counter.Options{
+		TestPatterns:      c.TestPatterns,
+		LanguageOverrides: c.Languages.Overrides,
+		Languages: counter.LanguageRules{
+			Rename:   c.Languages.Rename,
+			Groups:   c.Languages.Groups,
+			Exclude:  c.Languages.Exclude,
+			CodeOnly: c.Languages.CodeOnly,
+		},
+		FollowSymlinks:       c.FollowSymlinks,
+		Submodules:           submodules,
+		MaxFileSize:          c.MaxFileSize,
+		MaxAverageLineLength: c.MaxAverageLineLength,
+	}
+}
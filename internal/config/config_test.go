@@ -0,0 +1,110 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rjwalters/ghloc/internal/badge"
+	"github.com/rjwalters/ghloc/internal/counter"
+)
+
+func TestLoad_NoFile(t *testing.T) {
+	cfg, err := Load(filepath.Join(t.TempDir(), "config.json"))
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	opts := cfg.CounterOptions()
+	if opts.TestPatterns != nil || opts.Languages.CodeOnly {
+		t.Errorf("expected default options, got %+v", opts)
+	}
+	if len(cfg.DocThresholds()) != len(badge.DefaultDocThresholds) {
+		t.Errorf("DocThresholds() = %v, want the defaults", cfg.DocThresholds())
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(path, []byte(`{
+  "test_patterns": ["spec/**"],
+  "languages": {
+    "overrides": {"matlab/**/*.m": "MATLAB"},
+    "rename": {"C Header": "C"},
+    "groups": {"Frontend": ["JavaScript", "JSX", "TypeScript"]},
+    "exclude": ["markup", "data", "prose"],
+    "code_only": true
+  },
+  "follow_symlinks": true,
+  "submodules": "separate",
+  "max_file_size": 1000000,
+  "max_average_line_length": -1,
+  "doc_coverage_thresholds": [{"min": 30, "color": "green", "label": "ok"}, {"min": 15, "color": "yellow"}]
+}`), 0644)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	opts := cfg.CounterOptions()
+
+	if len(opts.TestPatterns) != 1 || opts.TestPatterns[0] != "spec/**" {
+		t.Errorf("TestPatterns: got %v", opts.TestPatterns)
+	}
+	if opts.LanguageOverrides["matlab/**/*.m"] != "MATLAB" {
+		t.Errorf("LanguageOverrides: got %v", opts.LanguageOverrides)
+	}
+	if opts.Languages.Rename["C Header"] != "C" {
+		t.Errorf("Rename: got %v", opts.Languages.Rename)
+	}
+	if len(opts.Languages.Groups["Frontend"]) != 3 {
+		t.Errorf("Groups: got %v", opts.Languages.Groups)
+	}
+	if len(opts.Languages.Exclude) != 3 || !opts.Languages.CodeOnly {
+		t.Errorf("Exclude/CodeOnly: got %v/%v", opts.Languages.Exclude, opts.Languages.CodeOnly)
+	}
+	if !opts.FollowSymlinks || opts.Submodules != counter.SubmodulesSeparate {
+		t.Errorf("FollowSymlinks/Submodules: got %v/%v", opts.FollowSymlinks, opts.Submodules)
+	}
+	if opts.MaxFileSize != 1000000 || opts.MaxAverageLineLength != -1 {
+		t.Errorf("MaxFileSize/MaxAverageLineLength: got %v/%v", opts.MaxFileSize, opts.MaxAverageLineLength)
+	}
+	if got := cfg.DocThresholds().Grade(20).Color; got != "yellow" {
+		t.Errorf("DocThresholds().Grade(20).Color = %q, want yellow", got)
+	}
+}
+
+func TestLoad_InvalidSubmoduleMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(path, []byte(`{"submodules": "vendor"}`), 0644)
+
+	if _, err := Load(path); err == nil {
+		t.Fatal("expected error for unknown submodule mode")
+	}
+}
+
+func TestLoad_InvalidDocCoverageColor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(path, []byte(`{"doc_coverage_thresholds": [{"min": 10, "color": "teal"}]}`), 0644)
+
+	if _, err := Load(path); err == nil {
+		t.Fatal("expected error for unknown badge color")
+	}
+}
+
+func TestLoad_LanguageInTwoGroups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(path, []byte(`{"languages": {"groups": {"A": ["Go"], "B": ["Go"]}}}`), 0644)
+
+	if _, err := Load(path); err == nil {
+		t.Fatal("expected error for language in two groups")
+	}
+}
+
+func TestLoad_InvalidJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(path, []byte("not json"), 0644)
+
+	if _, err := Load(path); err == nil {
+		t.Fatal("expected error for invalid JSON")
+	}
+}
@@ -0,0 +1,173 @@
+package counter
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/boyter/scc/v3/processor"
+)
+
+// cacheFormat is bumped whenever the cached statistics change meaning.
+const cacheFormat = 3
+
+// Cache stores per-file language and line counts between runs, keyed by path
+// and content hash, so unchanged files skip language detection and counting.
+// Entries are discarded when the scc version or the language configuration
+// changes. Save keeps only the files seen by the most recent count.
+type Cache struct {
+	path    string
+	version string
+	entries map[string]cachedFile // by path, as loaded
+	seen    map[string]cachedFile // by path, as used by the latest count
+
+	hits, misses int
+}
+
+// cachedFile is the outcome of detecting and counting one file. Skip is set
+// for binary files and files of unknown type.
+type cachedFile struct {
+	Hash       string     `json:"h"` // git blob SHA-1, plus sibling extensions for ambiguous files
+	Skip       SkipReason `json:"s,omitempty"`
+	Language   string     `json:"l,omitempty"`
+	Source     string     `json:"r,omitempty"` // what decided Language, for Explain and debug logs
+	Lines      int64      `json:"n,omitempty"`
+	Code       int64      `json:"c,omitempty"`
+	Comments   int64      `json:"m,omitempty"`
+	Blanks     int64      `json:"b,omitempty"`
+	Complexity int64      `json:"x,omitempty"`
+}
+
+// cacheFile is the on-disk form of a Cache.
+type cacheFile struct {
+	Version string                `json:"version"`
+	Files   map[string]cachedFile `json:"files"`
+}
+
+// OpenCache loads the cache stored at path. A missing file yields an empty cache.
+func OpenCache(path string) (*Cache, error) {
+	c := &Cache{path: path, entries: make(map[string]cachedFile), seen: make(map[string]cachedFile)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return c, nil
+		}
+		return nil, fmt.Errorf("read cache: %w", err)
+	}
+
+	var f cacheFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return c, nil // a corrupt cache is rebuilt, not fatal
+	}
+	c.version = f.Version
+	if f.Files != nil {
+		c.entries = f.Files
+	}
+	return c, nil
+}
+
+// Save writes the entries used by the latest count back to disk.
+func (c *Cache) Save() error {
+	data, err := json.Marshal(cacheFile{Version: c.version, Files: c.seen})
+	if err != nil {
+		return fmt.Errorf("marshal cache: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("create cache dir: %w", err)
+	}
+	if err := os.WriteFile(c.path, data, 0644); err != nil {
+		return fmt.Errorf("write cache: %w", err)
+	}
+	return nil
+}
+
+// Stats reports how many files the latest count found in the cache and how
+// many it had to process.
+func (c *Cache) Stats() (hits, misses int) {
+	return c.hits, c.misses
+}
+
+// HitRate returns the fraction of files served from the cache, or 0 if none
+// were looked up.
+func (c *Cache) HitRate() float64 {
+	if c.hits+c.misses == 0 {
+		return 0
+	}
+	return float64(c.hits) / float64(c.hits+c.misses)
+}
+
+// begin prepares the cache for a count, discarding all entries if they were
+// produced with a different scc version or language configuration.
+func (c *Cache) begin(version string) {
+	if c.version != version {
+		c.entries = make(map[string]cachedFile)
+		c.version = version
+	} else {
+		maps.Copy(c.entries, c.seen)
+	}
+	c.seen = make(map[string]cachedFile)
+	c.hits, c.misses = 0, 0
+}
+
+// lookup returns the cached outcome for the file at rel if its hash matches.
+func (c *Cache) lookup(rel, hash string) (cachedFile, bool) {
+	entry, ok := c.entries[rel]
+	if !ok || entry.Hash != hash {
+		c.misses++
+		return cachedFile{}, false
+	}
+	c.hits++
+	c.seen[rel] = entry
+	return entry, true
+}
+
+// store records the outcome for the file at rel.
+func (c *Cache) store(rel string, entry cachedFile) {
+	c.seen[rel] = entry
+}
+
+// cacheVersion fingerprints everything besides file contents that affects
+// detection and counting: the scc version, the cache format, the language
+// configuration and the .gitattributes language rules.
+func cacheVersion(opt Options, attributes []pathLanguage) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%v\n%+v\n%+v", opt.LanguageOverrides, opt.Languages, attributes) // fmt sorts map keys
+	return fmt.Sprintf("scc %s/format %d/%s", processor.Version, cacheFormat, hex.EncodeToString(h.Sum(nil)[:8]))
+}
+
+// blobHasher is implemented by file systems that already know the git blob
+// hash of their files, such as gitfs.FS.
+type blobHasher interface {
+	Hash(name string) (string, bool)
+}
+
+// blobHash computes the git blob SHA-1 of content, so hashes from a checkout
+// and from a git tree agree.
+func blobHash(content []byte) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", len(content))
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// signature summarizes extension counts for cache keys of files whose
+// detection depends on their siblings.
+func (e extCounts) signature() string {
+	var exts []string
+	for ext, n := range e {
+		if n > 0 {
+			exts = append(exts, ext)
+		}
+	}
+	slices.Sort(exts)
+	return strings.Join(exts, ",")
+}
@@ -0,0 +1,147 @@
+package counter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBlobHash(t *testing.T) {
+	// Hashes as printed by `git hash-object`
+	tests := map[string]string{
+		"":        "e69de29bb2d1d6434b8b29ae775ad8c2e48c5391",
+		"hello\n": "ce013625030ba8dba906f756967f9e9ca394464a",
+	}
+	for content, want := range tests {
+		if got := blobHash([]byte(content)); got != want {
+			t.Errorf("blobHash(%q) = %s, want %s", content, got, want)
+		}
+	}
+}
+
+func TestCount_Cache(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "util.go"), []byte("package main\n\nfunc util() {}\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("# Demo\n"), 0644)
+	cachePath := filepath.Join(t.TempDir(), "cache")
+
+	countWith := func(opt Options) (*LOCResult, int, int) {
+		t.Helper()
+		cache, err := OpenCache(cachePath)
+		if err != nil {
+			t.Fatalf("OpenCache() error: %v", err)
+		}
+		opt.Cache = cache
+		result, err := Count(dir, opt)
+		if err != nil {
+			t.Fatalf("Count() error: %v", err)
+		}
+		if err := cache.Save(); err != nil {
+			t.Fatalf("Save() error: %v", err)
+		}
+		hits, misses := cache.Stats()
+		return result, hits, misses
+	}
+
+	first, hits, misses := countWith(Options{})
+	if hits != 0 || misses != 3 {
+		t.Errorf("cold cache: got %d hits, %d misses; want 0, 3", hits, misses)
+	}
+
+	second, hits, misses := countWith(Options{})
+	if hits != 3 || misses != 0 {
+		t.Errorf("warm cache: got %d hits, %d misses; want 3, 0", hits, misses)
+	}
+	if second.TotalCode != first.TotalCode || second.TotalLines != first.TotalLines || second.TotalULOC != first.TotalULOC {
+		t.Errorf("cached result differs: %+v vs %+v", second, first)
+	}
+
+	// Only the changed file is processed again
+	os.WriteFile(filepath.Join(dir, "util.go"), []byte("package main\n\nfunc util() {}\nfunc more() {}\n"), 0644)
+	third, hits, misses := countWith(Options{})
+	if hits != 2 || misses != 1 {
+		t.Errorf("after edit: got %d hits, %d misses; want 2, 1", hits, misses)
+	}
+	if third.TotalCode != first.TotalCode+1 {
+		t.Errorf("after edit: TotalCode got %d, want %d", third.TotalCode, first.TotalCode+1)
+	}
+
+	// Changing the language configuration invalidates every entry
+	_, hits, misses = countWith(Options{Languages: LanguageRules{Rename: map[string]string{"Markdown": "Docs"}}})
+	if hits != 0 || misses != 3 {
+		t.Errorf("after config change: got %d hits, %d misses; want 0, 3", hits, misses)
+	}
+}
+
+func TestCount_CacheTracksSiblings(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "api.h"), []byte("int api();\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "api.cpp"), []byte("int api() { return 1; }\n"), 0644)
+	cache, err := OpenCache(filepath.Join(t.TempDir(), "cache"))
+	if err != nil {
+		t.Fatalf("OpenCache() error: %v", err)
+	}
+
+	result, err := Count(dir, Options{Cache: cache})
+	if err != nil {
+		t.Fatalf("Count() error: %v", err)
+	}
+	if got := fileLanguages(result)["api.h"]; got != "C++ Header" {
+		t.Fatalf("api.h beside api.cpp: got %q, want C++ Header", got)
+	}
+
+	// The header is unchanged, but its detection depended on api.cpp
+	os.Remove(filepath.Join(dir, "api.cpp"))
+	os.WriteFile(filepath.Join(dir, "api.c"), []byte("int api() { return 1; }\n"), 0644)
+	result, err = Count(dir, Options{Cache: cache})
+	if err != nil {
+		t.Fatalf("Count() error: %v", err)
+	}
+	if got := fileLanguages(result)["api.h"]; got != "C Header" {
+		t.Errorf("api.h beside api.c: got %q, want C Header", got)
+	}
+}
+
+func TestCount_CacheIgnoresSiblingsOfContentHeuristics(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "config.json"), []byte("{\"debug\": true}\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644)
+	cachePath := filepath.Join(t.TempDir(), "cache")
+
+	countWith := func() (hits, misses int) {
+		t.Helper()
+		cache, err := OpenCache(cachePath)
+		if err != nil {
+			t.Fatalf("OpenCache() error: %v", err)
+		}
+		if _, err := Count(dir, Options{Cache: cache}); err != nil {
+			t.Fatalf("Count() error: %v", err)
+		}
+		if err := cache.Save(); err != nil {
+			t.Fatalf("Save() error: %v", err)
+		}
+		return cache.Stats()
+	}
+	countWith()
+
+	// JSON detection reads only the file itself, so a new neighbour leaves
+	// its entry valid
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("# Demo\n"), 0644)
+	if hits, misses := countWith(); hits != 2 || misses != 1 {
+		t.Errorf("after adding a file: got %d hits, %d misses; want 2, 1", hits, misses)
+	}
+}
+
+func TestOpenCache_Corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache")
+	os.WriteFile(path, []byte("not json"), 0644)
+
+	cache, err := OpenCache(path)
+	if err != nil {
+		t.Fatalf("OpenCache() error: %v", err)
+	}
+	if hits, misses := cache.Stats(); hits != 0 || misses != 0 {
+		t.Errorf("expected empty cache, got %d hits, %d misses", hits, misses)
+	}
+}
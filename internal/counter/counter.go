@@ -1,14 +1,21 @@
 package counter
 
 import (
+	"context"
 	"fmt"
 	"io/fs"
+	"log/slog"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 
 	"github.com/boyter/scc/v3/processor"
+
+	"github.com/rjwalters/ghloc/internal/archive"
+	"github.com/rjwalters/ghloc/internal/gitfs"
 )
 
 var (
@@ -18,115 +25,527 @@ var (
 
 // Count walks the directory tree at dir and counts lines of code using scc.
 // The caller should ensure dir is a cloned repository. Thread-safe via mutex.
-func Count(dir string) (*LOCResult, error) {
-	initOnce.Do(func() {
-		processor.ProcessConstants()
+// opt customizes file classification; its zero value uses the defaults.
+func Count(dir string, opt Options) (*LOCResult, error) {
+	return CountContext(context.Background(), dir, opt)
+}
+
+// CountContext is like Count but stops when ctx is cancelled or its deadline
+// passes, checking before each directory and file. It then returns the files
+// counted so far in a result marked Incomplete, along with an error wrapping
+// ctx.Err(). A Cache used by an interrupted count should not be saved.
+func CountContext(ctx context.Context, dir string, opt Options) (*LOCResult, error) {
+	return count(ctx, os.DirFS(dir), dir, opt, (*counting).walkRoot)
+}
+
+// CountFS counts lines of code in an arbitrary file system, such as an
+// in-memory tree, an archive or a git tree, exactly as Count counts a directory.
+func CountFS(fsys fs.FS, opt Options) (*LOCResult, error) {
+	return CountFSContext(context.Background(), fsys, opt)
+}
+
+// CountFSContext is like CountFS but can be interrupted as CountContext can.
+func CountFSContext(ctx context.Context, fsys fs.FS, opt Options) (*LOCResult, error) {
+	return count(ctx, fsys, "", opt, (*counting).walkRoot)
+}
+
+// CountTree counts lines of code in the tree of a commit without checking it
+// out. repo is a local repository, bare or not, and rev any revision git
+// understands, such as a branch, tag or commit hash.
+func CountTree(repo, rev string, opt Options) (*LOCResult, error) {
+	return CountTreeContext(context.Background(), repo, rev, opt)
+}
+
+// CountTreeContext is like CountTree but can be interrupted as CountContext can.
+func CountTreeContext(ctx context.Context, repo, rev string, opt Options) (*LOCResult, error) {
+	fsys, err := gitfs.Open(repo, rev)
+	if err != nil {
+		return nil, err
+	}
+	defer fsys.Close()
+	return CountFSContext(ctx, fsys, opt)
+}
+
+// CountArchive counts lines of code in a tar, gzip-compressed tar or zip
+// archive without extracting it. A single top-level directory wrapping the
+// whole archive is treated as the root. Members larger than the size cap
+// are skipped. The archive is read in a single pass holding one member in
+// memory at a time, so symlinks are never followed: every regular file is
+// counted once, at its own path.
+func CountArchive(path string, opt Options) (*LOCResult, error) {
+	return CountArchiveContext(context.Background(), path, opt)
+}
+
+// CountArchiveContext is like CountArchive but can be interrupted as
+// CountContext can.
+func CountArchiveContext(ctx context.Context, path string, opt Options) (*LOCResult, error) {
+	fsys, err := archive.Open(path, opt.maxFileSize())
+	if err != nil {
+		return nil, err
+	}
+	defer fsys.Close()
+	return count(ctx, fsys, "", opt, func(c *counting) error {
+		return c.walkArchive(fsys)
 	})
+}
 
+// count counts fsys, visiting its files with walk. dir is the operating
+// system directory backing fsys, if any, and is used to resolve symlinks
+// that point outside the tree.
+func count(ctx context.Context, fsys fs.FS, dir string, opt Options, walk func(*counting) error) (*LOCResult, error) {
+	initProcessor()
 	countMu.Lock()
 	defer countMu.Unlock()
 
-	langTotals := make(map[string]*LanguageStats)
+	c, err := newCounting(fsys, dir, opt)
+	if err != nil {
+		return nil, err
+	}
+	c.ctx = ctx
+	if err := walk(c); err != nil {
+		if ctx.Err() == nil {
+			return nil, fmt.Errorf("walk dir: %w", err)
+		}
+		result := c.result()
+		result.Incomplete = true
+		c.log.Warn("count interrupted", "files", result.TotalFiles, "err", err)
+		return result, fmt.Errorf("count interrupted: %w", err)
+	}
+	result := c.result()
+	c.log.Info("count finished", "files", result.TotalFiles, "code", result.TotalCode, "skipped", len(result.Skipped))
+	return result, nil
+}
 
-	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
+// initProcessor loads scc's language database on first use.
+func initProcessor() {
+	initOnce.Do(func() {
+		processor.ProcessConstants()
+		buildLanguageIndex()
+	})
+}
+
+// counting holds the state of a single Count call.
+type counting struct {
+	ctx          context.Context
+	fsys         fs.FS
+	dir          string // OS directory backing fsys, or ""
+	opt          Options
+	log          *slog.Logger
+	testPatterns []string
+	mapper       *languageMapper
+	resolver     *languageResolver
+	submodules   []submodule
+	main         *tally
+	subTallies   map[string]*tally // keyed by submodule path
+	visited      map[string]bool   // resolved paths already walked when following symlinks
+	skipped      []SkippedFile
+}
+
+func newCounting(fsys fs.FS, dir string, opt Options) (*counting, error) {
+	resolver, err := newLanguageResolver(fsys, opt.LanguageOverrides)
+	if err != nil {
+		return nil, err
+	}
+	submodules, err := loadGitmodules(fsys, ".gitmodules")
+	if err != nil {
+		return nil, err
+	}
+	if opt.Cache != nil {
+		opt.Cache.begin(cacheVersion(opt, resolver.attributes))
+	}
+
+	return &counting{
+		ctx:          context.Background(),
+		fsys:         fsys,
+		dir:          dir,
+		opt:          opt,
+		log:          opt.logger(),
+		testPatterns: opt.testPatterns(),
+		mapper:       newLanguageMapper(opt.Languages),
+		resolver:     resolver,
+		submodules:   submodules,
+		main:         newTally(),
+		subTallies:   make(map[string]*tally),
+		visited:      make(map[string]bool),
+	}, nil
+}
+
+// walkRoot counts every file in the tree.
+func (c *counting) walkRoot() error {
+	return c.walkDir(".")
+}
+
+// walkArchive counts every regular file in a, in archive order, applying
+// the same exclusions as walkDir, then sorts the results into the order
+// walkDir would have produced.
+func (c *counting) walkArchive(a *archive.FS) error {
+	defer c.sortByPath()
+	return a.Walk(func(rel string, size int64) error {
+		if err := c.ctx.Err(); err != nil {
 			return err
 		}
-
-		// Skip .git directory
-		if d.IsDir() && d.Name() == ".git" {
-			return fs.SkipDir
+		if slices.Contains(strings.Split(rel, "/"), ".git") {
+			return nil
 		}
-		if d.IsDir() {
+		if c.excludedDir(rel) {
 			return nil
 		}
+		if c.opt.Submodules == SubmodulesExclude {
+			if _, ok := submoduleFor(c.submodules, rel); ok {
+				c.log.Debug("excluded submodule", "path", rel)
+				return nil
+			}
+		}
+		c.countFile(rel, size)
+		return nil
+	})
+}
 
-		// Skip symlinks
-		if d.Type()&fs.ModeSymlink != 0 {
+// sortByPath puts counted and skipped files into the depth-first, sorted
+// order of a directory walk.
+func (c *counting) sortByPath() {
+	byPath := func(a, b string) int {
+		return slices.Compare(strings.Split(a, "/"), strings.Split(b, "/"))
+	}
+	sortFiles := func(files []FileStats) {
+		slices.SortStableFunc(files, func(a, b FileStats) int { return byPath(a.Path, b.Path) })
+	}
+	sortFiles(c.main.files)
+	for _, t := range c.subTallies {
+		sortFiles(t.files)
+	}
+	slices.SortStableFunc(c.skipped, func(a, b SkippedFile) int { return byPath(a.Path, b.Path) })
+}
+
+// walkDir counts every file below the slash-separated path rel ("." for the root).
+func (c *counting) walkDir(rel string) error {
+	var realDir string
+	if c.opt.FollowSymlinks {
+		// Guard against symlink cycles and directories linked more than once
+		var err error
+		realDir, err = c.realPath(rel)
+		if err != nil {
+			return err
+		}
+		if c.visited[realDir] {
 			return nil
 		}
+		c.visited[realDir] = true
+	}
 
-		content, err := os.ReadFile(path)
-		if err != nil {
-			return nil // skip unreadable files
+	if err := c.ctx.Err(); err != nil {
+		return err
+	}
+	entries, err := fs.ReadDir(c.fsys, rel)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range entries {
+		if err := c.ctx.Err(); err != nil {
+			return err
 		}
+		name := d.Name()
+		childRel := path.Join(rel, name)
 
-		// Detect language
-		languages, ext := processor.DetectLanguage(d.Name())
-		if len(languages) == 0 {
-			return nil // unknown file type, skip
+		// Skip .git directories, and .git files in submodule checkouts
+		if name == ".git" {
+			continue
+		}
+		if c.excludedDir(childRel) {
+			c.log.Debug("excluded directory", "path", childRel)
+			continue
+		}
+		if c.opt.Submodules == SubmodulesExclude {
+			if _, ok := submoduleFor(c.submodules, childRel); ok {
+				c.log.Debug("excluded submodule", "path", childRel)
+				continue
+			}
 		}
 
-		language := languages[0]
-		if len(languages) > 1 {
-			language = determineLanguage(d.Name(), languages, content)
+		mode := d.Type()
+		var info fs.FileInfo // of the link target, when following a symlink
+		if mode&fs.ModeSymlink != 0 {
+			if !c.opt.FollowSymlinks {
+				c.log.Debug("skipped symlink", "path", childRel)
+				continue
+			}
+			if info, err = fs.Stat(c.fsys, childRel); err != nil {
+				c.log.Debug("skipped dangling symlink", "path", childRel, "err", err)
+				continue
+			}
+			mode = info.Mode().Type()
 		}
 
-		// Create and count a FileJob
-		job := &processor.FileJob{
-			Filename:  d.Name(),
-			Extension: ext,
-			Location:  path,
-			Language:  language,
-			Content:   content,
-			Bytes:     int64(len(content)),
+		switch {
+		case mode.IsDir():
+			if err := c.walkDir(childRel); err != nil {
+				return err
+			}
+		case mode.IsRegular():
+			if c.opt.FollowSymlinks && !c.firstVisit(realDir, childRel, d) {
+				c.log.Debug("skipped duplicate", "path", childRel)
+				continue // already counted through another link
+			}
+			if info == nil {
+				if info, err = d.Info(); err != nil {
+					c.skip(childRel, SkipUnreadable, err)
+					continue
+				}
+			}
+			c.countFile(childRel, info.Size())
 		}
+	}
+	return nil
+}
 
-		processor.CountStats(job)
+// excludedDir reports whether rel is, or lies inside, one of
+// Options.ExcludeDirs.
+func (c *counting) excludedDir(rel string) bool {
+	return slices.ContainsFunc(c.opt.ExcludeDirs, func(dir string) bool {
+		dir = path.Clean(dir)
+		return rel == dir || strings.HasPrefix(rel, dir+"/")
+	})
+}
 
-		if job.Binary {
-			return nil
+// firstVisit records the resolved path of the file rel in realDir, reporting
+// whether it has not been seen before.
+func (c *counting) firstVisit(realDir, rel string, d fs.DirEntry) bool {
+	real := path.Join(realDir, d.Name())
+	if d.Type()&fs.ModeSymlink != 0 {
+		var err error
+		if real, err = c.realPath(rel); err != nil {
+			return false
 		}
+	}
+	if c.visited[real] {
+		return false
+	}
+	c.visited[real] = true
+	return true
+}
+
+// realPath returns a canonical, symlink-free name for rel. For directories
+// on disk this is the absolute OS path, so links leaving the tree resolve too.
+func (c *counting) realPath(rel string) (string, error) {
+	if c.dir != "" {
+		return filepath.EvalSymlinks(filepath.Join(c.dir, filepath.FromSlash(rel)))
+	}
+	if linkFS, ok := c.fsys.(fs.ReadLinkFS); ok {
+		return resolveLinks(linkFS, rel)
+	}
+	return rel, nil
+}
 
-		// Accumulate per-language stats
-		stats, ok := langTotals[language]
+// resolveLinks resolves the symlinks in rel within fsys. Links with absolute
+// targets or targets outside the tree cannot be resolved.
+func resolveLinks(fsys fs.ReadLinkFS, rel string) (string, error) {
+	resolved := "."
+	rest := strings.Split(rel, "/")
+	for hops := 0; len(rest) > 0; {
+		next := path.Join(resolved, rest[0])
+		rest = rest[1:]
+		info, err := fsys.Lstat(next)
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&fs.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+
+		if hops++; hops > 40 {
+			return "", fmt.Errorf("%s: too many levels of symbolic links", rel)
+		}
+		target, err := fsys.ReadLink(next)
+		if err != nil {
+			return "", err
+		}
+		target = path.Join(resolved, target)
+		if path.IsAbs(target) || !fs.ValidPath(target) {
+			return "", fmt.Errorf("%s: link leaves the tree", rel)
+		}
+		rest = append(strings.Split(target, "/"), rest...)
+		resolved = "."
+	}
+	return resolved, nil
+}
+
+// countFile counts a single file of the given size and attributes it to the
+// right tallies.
+func (c *counting) countFile(rel string, size int64) {
+	e, content := c.classify(rel, size)
+	switch {
+	case e.Skipped != "":
+		c.skip(rel, e.Skipped, e.Err)
+		return
+	case e.Excluded != "":
+		c.log.Debug("excluded file", "path", rel, "detected", e.Detected, "reason", e.Excluded)
+		return
+	}
+	c.log.Debug("counted file", "path", rel, "language", e.Language, "detected", e.Detected,
+		"source", e.Source, "candidates", e.Candidates, "cached", e.Cached)
+
+	if e.Submodule != "" && c.opt.Submodules != SubmodulesExclude {
+		t, ok := c.subTallies[e.Submodule]
 		if !ok {
-			stats = &LanguageStats{Language: language}
-			langTotals[language] = stats
+			t = newTally()
+			c.subTallies[e.Submodule] = t
 		}
-		stats.Lines += job.Lines
-		stats.Code += job.Code
-		stats.Comments += job.Comment
-		stats.Blanks += job.Blank
-		stats.Files++
+		t.add(e.Stats, e.Category, e.InTotals, content)
+	}
+	if e.Submodule == "" || c.opt.Submodules != SubmodulesSeparate {
+		c.main.add(e.Stats, e.Category, e.InTotals, content)
+	}
+}
 
-		return nil
-	})
+// classify decides whether and how the file at rel, of the given size, is
+// counted. content is returned only for files that are counted.
+func (c *counting) classify(rel string, size int64) (e *Explanation, content []byte) {
+	e = &Explanation{Path: rel}
+	if sub, ok := submoduleFor(c.submodules, rel); ok {
+		e.Submodule = sub.path
+	}
+	if limit := c.opt.maxFileSize(); limit > 0 && size > limit {
+		e.Skipped = SkipTooLarge
+		return e, nil
+	}
+	content, err := fs.ReadFile(c.fsys, rel)
 	if err != nil {
-		return nil, fmt.Errorf("walk dir: %w", err)
+		e.Skipped, e.Err = SkipUnreadable, err
+		return e, nil
 	}
 
-	result := &LOCResult{}
-	for _, stats := range langTotals {
-		result.Languages = append(result.Languages, *stats)
-		result.TotalLines += stats.Lines
-		result.TotalCode += stats.Code
-		result.TotalComments += stats.Comments
-		result.TotalBlanks += stats.Blanks
-		result.TotalFiles += stats.Files
+	counted := c.analyze(e, content)
+	if counted.Skip != "" {
+		e.Skipped = counted.Skip
+		return e, nil
+	}
+	e.Detected, e.Source = counted.Language, counted.Source
+	if limit := c.opt.maxAverageLineLength(); limit > 0 && counted.Lines > 0 && int64(len(content))/counted.Lines > int64(limit) {
+		e.Skipped = SkipMinified
+		return e, nil
 	}
 
-	return result, nil
+	name, category, ok := c.mapper.resolve(counted.Language)
+	if !ok {
+		e.Excluded = "language excluded by configuration"
+		return e, nil
+	}
+	e.Language, e.Category = name, category
+	e.Stats = FileStats{
+		Path:       rel,
+		Language:   name,
+		Lines:      counted.Lines,
+		Code:       counted.Code,
+		Comments:   counted.Comments,
+		Blanks:     counted.Blanks,
+		Bytes:      int64(len(content)),
+		Complexity: counted.Complexity,
+		Test:       matchAny(c.testPatterns, rel),
+	}
+	e.InTotals = c.mapper.countsTowardTotals(category)
+	return e, content
 }
 
-// determineLanguage tries to pick the best language when multiple are possible.
-func determineLanguage(filename string, languages []string, content []byte) string {
-	// Use file extension heuristics
-	ext := strings.ToLower(filepath.Ext(filename))
-	switch ext {
-	case ".h":
-		// Could be C or C++; default to C
-		return "C Header"
-	case ".m":
-		return "Objective-C"
+// skip records a file that is not counted.
+func (c *counting) skip(rel string, reason SkipReason, err error) {
+	if reason == SkipUnreadable {
+		c.log.Warn("unreadable file", "path", rel, "err", err)
+	} else {
+		c.log.Debug("skipped file", "path", rel, "reason", reason)
+	}
+	c.skipped = append(c.skipped, SkippedFile{Path: rel, Reason: reason, Err: err})
+}
+
+// analyze detects the language of the file e describes and counts its lines,
+// reusing the cached outcome when the file is unchanged. A non-empty Skip
+// means the file is not counted. It records the candidate languages and
+// whether the cache was used in e.
+func (c *counting) analyze(e *Explanation, content []byte) cachedFile {
+	rel := e.Path
+	filename := path.Base(rel)
+	languages, ext := processor.DetectLanguage(filename)
+	e.Candidates = languages
+	if len(languages) == 0 {
+		return cachedFile{Skip: SkipUnknownLanguage}
+	}
+
+	var key string
+	if c.opt.Cache != nil {
+		key = c.cacheKey(rel, content)
+		if cached, ok := c.opt.Cache.lookup(rel, key); ok {
+			e.Cached = true
+			return cached
+		}
+	}
+
+	counted := cachedFile{Hash: key}
+	// An empty language is an extensionless file without a recognizable
+	// shebang or modeline
+	switch language, source := c.resolver.resolve(rel, languages, content); language {
+	case "":
+		counted.Skip = SkipUnknownLanguage
+	default:
+		job := &processor.FileJob{
+			Filename:  filename,
+			Extension: ext,
+			Location:  rel,
+			Language:  language,
+			Content:   content,
+			Bytes:     int64(len(content)),
+		}
+		processor.CountStats(job)
+
+		if job.Binary {
+			counted.Skip = SkipBinary
+		} else {
+			counted.Language = language
+			counted.Source = source
+			counted.Lines = job.Lines
+			counted.Code = job.Code
+			counted.Comments = job.Comment
+			counted.Blanks = job.Blank
+			counted.Complexity = job.Complexity
+		}
 	}
 
-	// For other ambiguous cases, try scc's built-in determination
-	// Pass a reasonable chunk of content for keyword analysis
-	sample := content
-	if len(sample) > 20000 {
-		sample = sample[:20000]
+	if c.opt.Cache != nil {
+		c.opt.Cache.store(rel, counted)
 	}
+	return counted
+}
 
-	return processor.DetermineLanguage(filename, languages[0], languages, sample)
+// cacheKey identifies the content of the file at rel, and for extensions
+// resolved from neighbouring files, the extensions beside it.
+func (c *counting) cacheKey(rel string, content []byte) string {
+	hash, ok := "", false
+	if hasher, isHasher := c.fsys.(blobHasher); isHasher {
+		hash, ok = hasher.Hash(rel)
+	}
+	if !ok {
+		hash = blobHash(content)
+	}
+	if usesSiblings(rel) {
+		hash += ":" + c.resolver.siblingExts(path.Dir(rel)).signature()
+	}
+	return hash
+}
+
+// result assembles the LOCResult, with one entry per submodule that had files.
+func (c *counting) result() *LOCResult {
+	result := c.main.result()
+	result.Skipped = c.skipped
+	for _, sub := range c.submodules {
+		t, ok := c.subTallies[sub.path]
+		if !ok {
+			continue
+		}
+		result.Submodules = append(result.Submodules, SubmoduleResult{
+			Name:      sub.name,
+			Path:      sub.path,
+			LOCResult: *t.result(),
+		})
+	}
+	return result
 }
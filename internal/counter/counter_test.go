@@ -1,16 +1,28 @@
 package counter
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
 	"os"
+	"os/exec"
+	"path"
 	"path/filepath"
+	"slices"
 	"testing"
+	"testing/fstest"
 )
 
 func TestCount_GhlocRepo(t *testing.T) {
 	// Count the ghloc repo itself — we know it has Go files
 	repoRoot := findRepoRoot(t)
 
-	result, err := Count(repoRoot)
+	result, err := Count(repoRoot, Options{})
 	if err != nil {
 		t.Fatalf("Count() error: %v", err)
 	}
@@ -54,7 +66,7 @@ func TestCount_GhlocRepo(t *testing.T) {
 func TestCount_EmptyDir(t *testing.T) {
 	dir := t.TempDir()
 
-	result, err := Count(dir)
+	result, err := Count(dir, Options{})
 	if err != nil {
 		t.Fatalf("Count() error: %v", err)
 	}
@@ -84,7 +96,7 @@ func main() {
 		t.Fatal(err)
 	}
 
-	result, err := Count(dir)
+	result, err := Count(dir, Options{})
 	if err != nil {
 		t.Fatalf("Count() error: %v", err)
 	}
@@ -118,7 +130,7 @@ func TestCount_SkipsGitDir(t *testing.T) {
 	// Create a real Go file outside .git
 	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\nfunc main() {}\n"), 0644)
 
-	result, err := Count(dir)
+	result, err := Count(dir, Options{})
 	if err != nil {
 		t.Fatalf("Count() error: %v", err)
 	}
@@ -141,7 +153,7 @@ func TestCount_SkipsBinaryFiles(t *testing.T) {
 	// Write a Go file
 	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\nfunc main() {}\n"), 0644)
 
-	result, err := Count(dir)
+	result, err := Count(dir, Options{})
 	if err != nil {
 		t.Fatalf("Count() error: %v", err)
 	}
@@ -156,6 +168,137 @@ func TestCount_SkipsBinaryFiles(t *testing.T) {
 	}
 }
 
+// failingFS is a MapFS whose file fail cannot be read.
+type failingFS struct {
+	fstest.MapFS
+	fail string
+}
+
+func (f failingFS) Open(name string) (fs.File, error) {
+	if name == f.fail {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	return f.MapFS.Open(name)
+}
+
+func (f failingFS) ReadFile(name string) ([]byte, error) {
+	if name == f.fail {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	return f.MapFS.ReadFile(name)
+}
+
+// cancellingFS cancels a count once it has read a number of Go files.
+type cancellingFS struct {
+	fstest.MapFS
+	cancel func()
+	after  int
+}
+
+func (f *cancellingFS) ReadFile(name string) ([]byte, error) {
+	if path.Ext(name) == ".go" {
+		if f.after--; f.after == 0 {
+			f.cancel()
+		}
+	}
+	return f.MapFS.ReadFile(name)
+}
+
+func TestCountContext_Cancel(t *testing.T) {
+	mem := fstest.MapFS{}
+	for i := range 10 {
+		mem[fmt.Sprintf("pkg%d/file.go", i)] = &fstest.MapFile{Data: []byte("package pkg\n")}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	result, err := CountFSContext(ctx, &cancellingFS{MapFS: mem, cancel: cancel, after: 3}, Options{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if result == nil || !result.Incomplete {
+		t.Fatalf("expected a partial result marked incomplete, got %+v", result)
+	}
+	if result.TotalFiles != 3 {
+		t.Errorf("expected the 3 files read before cancelling, got %d", result.TotalFiles)
+	}
+
+	full, err := CountFS(mem, Options{})
+	if err != nil || full.Incomplete || full.TotalFiles != 10 {
+		t.Errorf("CountFS() = %d files, incomplete %v, %v", full.TotalFiles, full.Incomplete, err)
+	}
+}
+
+func TestCountContext_Deadline(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644)
+
+	ctx, cancel := context.WithTimeout(context.Background(), -1)
+	defer cancel()
+	result, err := CountContext(ctx, dir, Options{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if !result.Incomplete || result.TotalFiles != 0 {
+		t.Errorf("expected an empty incomplete result, got %d files, incomplete %v", result.TotalFiles, result.Incomplete)
+	}
+}
+
+func TestCount_SkipReasons(t *testing.T) {
+	binary := []byte("int x;\x00\x01\x02\x03\n")
+	mem := fstest.MapFS{
+		"main.go":        {Data: []byte("package main\nfunc main() {}\n")},
+		"image.c":        {Data: binary},
+		"data.unknownx":  {Data: []byte("hello\n")},
+		"NOTES":          {Data: []byte("no shebang here\n")},
+		"huge.go":        {Data: bytes.Repeat([]byte("var x = 1\n"), 200)},
+		"dist/app.js":    {Data: append(bytes.Repeat([]byte("a=1;"), 300), '\n')},
+		"locked.go":      {Data: []byte("package main\n")},
+		"src/normal.js":  {Data: []byte("const a = 1;\nconst b = 2;\n")},
+		"src/too/big.md": {Data: bytes.Repeat([]byte("x"), 2000)},
+	}
+
+	result, err := CountFS(failingFS{MapFS: mem, fail: "locked.go"}, Options{MaxFileSize: 1500})
+	if err != nil {
+		t.Fatalf("CountFS() error: %v", err)
+	}
+
+	want := map[string]SkipReason{
+		"image.c":        SkipBinary,
+		"data.unknownx":  SkipUnknownLanguage,
+		"NOTES":          SkipUnknownLanguage,
+		"huge.go":        SkipTooLarge,
+		"src/too/big.md": SkipTooLarge,
+		"dist/app.js":    SkipMinified,
+		"locked.go":      SkipUnreadable,
+	}
+	got := make(map[string]SkipReason)
+	for _, s := range result.Skipped {
+		got[s.Path] = s.Reason
+		if s.Reason == SkipUnreadable && !errors.Is(s.Err, fs.ErrPermission) {
+			t.Errorf("%s: expected the read error to be kept, got %v", s.Path, s.Err)
+		}
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("Skipped = %v, want %v", got, want)
+	}
+	if result.TotalFiles != 2 {
+		t.Errorf("expected 2 counted files, got %d: %v", result.TotalFiles, fileLanguages(result))
+	}
+	if n := result.SkippedByReason()[SkipTooLarge]; n != 2 {
+		t.Errorf("SkippedByReason()[too large] = %d, want 2", n)
+	}
+
+	// Negative limits disable both guards
+	result, err = CountFS(mem, Options{MaxFileSize: -1, MaxAverageLineLength: -1})
+	if err != nil {
+		t.Fatalf("CountFS() error: %v", err)
+	}
+	if result.TotalFiles != 6 {
+		t.Errorf("without guards: expected 6 counted files, got %d: %v", result.TotalFiles, fileLanguages(result))
+	}
+}
+
 // findRepoRoot walks up from the test file to find the repo root (contains go.mod).
 func findRepoRoot(t *testing.T) string {
 	t.Helper()
@@ -174,3 +317,412 @@ func findRepoRoot(t *testing.T) string {
 		dir = parent
 	}
 }
+
+func TestCount_FileStats(t *testing.T) {
+	dir := t.TempDir()
+
+	os.MkdirAll(filepath.Join(dir, "pkg"), 0755)
+	os.WriteFile(filepath.Join(dir, "pkg", "a.go"), []byte("package pkg\n\n// A does a thing\nfunc A() {\n\tif true {\n\t}\n}\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\nfunc main() {}\n"), 0644)
+
+	result, err := Count(dir, Options{})
+	if err != nil {
+		t.Fatalf("Count() error: %v", err)
+	}
+
+	if len(result.Files) != 2 {
+		t.Fatalf("expected 2 file records, got %d", len(result.Files))
+	}
+	var found bool
+	for _, f := range result.Files {
+		if f.Path != "pkg/a.go" {
+			continue
+		}
+		found = true
+		if f.Language != "Go" {
+			t.Errorf("Language: got %q, want Go", f.Language)
+		}
+		if f.Code != 5 || f.Comments != 1 || f.Blanks != 1 {
+			t.Errorf("got code=%d comments=%d blanks=%d, want 5/1/1", f.Code, f.Comments, f.Blanks)
+		}
+		if f.Bytes == 0 {
+			t.Error("expected Bytes > 0")
+		}
+		if f.Complexity == 0 {
+			t.Error("expected Complexity > 0")
+		}
+	}
+	if !found {
+		t.Errorf("expected record for pkg/a.go, got %+v", result.Files)
+	}
+}
+
+func TestCount_BytesAndULOC(t *testing.T) {
+	dir := t.TempDir()
+
+	// Two files sharing every line except the package clause
+	body := "\nfunc init() {\n\tprintln(1)\n}\n"
+	os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n"+body), 0644)
+	os.WriteFile(filepath.Join(dir, "b.go"), []byte("package b\n"+body), 0644)
+
+	result, err := Count(dir, Options{})
+	if err != nil {
+		t.Fatalf("Count() error: %v", err)
+	}
+
+	wantBytes := int64(2*len(body) + 20)
+	if result.TotalBytes != wantBytes {
+		t.Errorf("TotalBytes: got %d, want %d", result.TotalBytes, wantBytes)
+	}
+	// Unique lines: "package a", "package b", "", "func init() {", "\tprintln(1)", "}"
+	if result.TotalULOC != 6 {
+		t.Errorf("TotalULOC: got %d, want 6", result.TotalULOC)
+	}
+	if result.Languages[0].ULOC != 6 || result.Languages[0].Bytes != wantBytes {
+		t.Errorf("Go stats: got ULOC=%d Bytes=%d", result.Languages[0].ULOC, result.Languages[0].Bytes)
+	}
+	if got, want := result.Dryness(), 6.0/10.0; got != want {
+		t.Errorf("Dryness(): got %v, want %v", got, want)
+	}
+}
+
+func TestCount_TestCodeSplit(t *testing.T) {
+	dir := t.TempDir()
+
+	os.MkdirAll(filepath.Join(dir, "tests"), 0755)
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\nfunc main() {}\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "main_test.go"), []byte("package main\nimport \"testing\"\nfunc TestMain(t *testing.T) {}\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "app.py"), []byte("x = 1\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "tests", "check.py"), []byte("assert True\nassert 1\n"), 0644)
+
+	result, err := Count(dir, Options{})
+	if err != nil {
+		t.Fatalf("Count() error: %v", err)
+	}
+
+	if result.TotalTestCode != 5 {
+		t.Errorf("TotalTestCode: got %d, want 5", result.TotalTestCode)
+	}
+	for _, lang := range result.Languages {
+		switch lang.Language {
+		case "Go":
+			if lang.TestCode != 3 || lang.TestFiles != 1 {
+				t.Errorf("Go: got TestCode=%d TestFiles=%d, want 3/1", lang.TestCode, lang.TestFiles)
+			}
+		case "Python":
+			if lang.TestCode != 2 || lang.Code-lang.TestCode != 1 {
+				t.Errorf("Python: got TestCode=%d Code=%d, want 2/3", lang.TestCode, lang.Code)
+			}
+		}
+	}
+
+	// Custom patterns replace the defaults
+	result, err = Count(dir, Options{TestPatterns: []string{"app.py"}})
+	if err != nil {
+		t.Fatalf("Count() error: %v", err)
+	}
+	if result.TotalTestCode != 1 {
+		t.Errorf("custom patterns: TotalTestCode got %d, want 1", result.TotalTestCode)
+	}
+}
+
+func TestCount_Symlinks(t *testing.T) {
+	dir := t.TempDir()
+
+	os.MkdirAll(filepath.Join(dir, "src"), 0755)
+	os.WriteFile(filepath.Join(dir, "src", "main.go"), []byte("package main\nfunc main() {}\n"), 0644)
+	outside := t.TempDir()
+	os.WriteFile(filepath.Join(outside, "lib.go"), []byte("package lib\n"), 0644)
+
+	// A link to a directory outside the tree, a cycle back to the root, and a
+	// second link to an already counted file.
+	if err := os.Symlink(outside, filepath.Join(dir, "vendored")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	os.Symlink(dir, filepath.Join(dir, "src", "loop"))
+	os.Symlink(filepath.Join(dir, "src", "main.go"), filepath.Join(dir, "alias.go"))
+	os.Symlink(filepath.Join(dir, "missing.go"), filepath.Join(dir, "dangling.go"))
+
+	result, err := Count(dir, Options{})
+	if err != nil {
+		t.Fatalf("Count() error: %v", err)
+	}
+	if result.TotalFiles != 1 {
+		t.Errorf("without FollowSymlinks: expected 1 file, got %d", result.TotalFiles)
+	}
+
+	result, err = Count(dir, Options{FollowSymlinks: true})
+	if err != nil {
+		t.Fatalf("Count() error: %v", err)
+	}
+	got := fileLanguages(result)
+	if result.TotalFiles != 2 {
+		t.Errorf("with FollowSymlinks: expected 2 files, got %d: %v", result.TotalFiles, got)
+	}
+	if _, ok := got["vendored/lib.go"]; !ok {
+		t.Errorf("expected file reached through directory symlink, got %v", got)
+	}
+}
+
+func TestCount_Submodules(t *testing.T) {
+	dir := t.TempDir()
+
+	os.WriteFile(filepath.Join(dir, ".gitmodules"), []byte(`[submodule "third_party/lib"]
+	path = third_party/lib
+	url = https://example.com/lib.git
+`), 0644)
+	os.MkdirAll(filepath.Join(dir, "third_party", "lib"), 0755)
+	os.WriteFile(filepath.Join(dir, "third_party", "lib", ".git"), []byte("gitdir: ../../.git/modules/lib\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "third_party", "lib", "lib.go"), []byte("package lib\nfunc A() {}\nfunc B() {}\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\nfunc main() {}\n"), 0644)
+
+	tests := []struct {
+		mode          SubmoduleMode
+		wantCode      int64
+		wantSubmodule bool
+	}{
+		{SubmodulesInclude, 5, true},
+		{SubmodulesExclude, 2, false},
+		{SubmodulesSeparate, 2, true},
+	}
+	for _, tt := range tests {
+		result, err := Count(dir, Options{Submodules: tt.mode})
+		if err != nil {
+			t.Fatalf("%s: Count() error: %v", tt.mode, err)
+		}
+		if result.TotalCode != tt.wantCode {
+			t.Errorf("%s: TotalCode got %d, want %d", tt.mode, result.TotalCode, tt.wantCode)
+		}
+		if got := len(result.Submodules) == 1; got != tt.wantSubmodule {
+			t.Fatalf("%s: expected submodule entry %v, got %+v", tt.mode, tt.wantSubmodule, result.Submodules)
+		}
+		if tt.wantSubmodule {
+			sub := result.Submodules[0]
+			if sub.Name != "third_party/lib" || sub.Path != "third_party/lib" {
+				t.Errorf("%s: unexpected submodule %q at %q", tt.mode, sub.Name, sub.Path)
+			}
+			if sub.TotalCode != 3 || sub.TotalFiles != 1 {
+				t.Errorf("%s: submodule totals got code=%d files=%d, want 3/1", tt.mode, sub.TotalCode, sub.TotalFiles)
+			}
+		}
+	}
+}
+
+// sampleTree is a small repository used to check that every file system is
+// counted the same way, including detection that reads other files.
+var sampleTree = map[string]string{
+	".gitattributes":     "*.tmpl linguist-language=HTML\n",
+	"main.go":            "package main\n\n// main runs.\nfunc main() {}\n",
+	"main_test.go":       "package main\n",
+	"native/api.h":       "int api();\n",
+	"native/api.cpp":     "int api() { return 1; }\n",
+	"web/page.tmpl":      "<p>hi</p>\n",
+	"scripts/build":      "#!/usr/bin/env bash\necho hi\n",
+	"assets/logo.png":    "\x89PNG\r\n\x1a\n\x00\x00\x00",
+	"docs/guide.md":      "# Guide\n\ntext\n",
+	"vendor/lib/lib.py":  "print('x')\n",
+	".git/config":        "[core]\n",
+	"empty-dir/.gitkeep": "",
+}
+
+func TestCountFS_MatchesCount(t *testing.T) {
+	dir := t.TempDir()
+	mem := fstest.MapFS{}
+	for name, content := range sampleTree {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755)
+		os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		mem[name] = &fstest.MapFile{Data: []byte(content), Mode: 0644}
+	}
+
+	fromDir, err := Count(dir, Options{})
+	if err != nil {
+		t.Fatalf("Count() error: %v", err)
+	}
+	fromFS, err := CountFS(mem, Options{})
+	if err != nil {
+		t.Fatalf("CountFS() error: %v", err)
+	}
+
+	if got, want := fileLanguages(fromFS), fileLanguages(fromDir); !maps.Equal(got, want) {
+		t.Errorf("CountFS files = %v, Count files = %v", got, want)
+	}
+	if fromFS.TotalCode != fromDir.TotalCode || fromFS.TotalFiles != fromDir.TotalFiles || fromFS.TotalULOC != fromDir.TotalULOC {
+		t.Errorf("CountFS totals = %d code/%d files/%d uloc, Count = %d/%d/%d",
+			fromFS.TotalCode, fromFS.TotalFiles, fromFS.TotalULOC,
+			fromDir.TotalCode, fromDir.TotalFiles, fromDir.TotalULOC)
+	}
+	if got := fileLanguages(fromFS)["native/api.h"]; got != "C++ Header" {
+		t.Errorf("api.h beside api.cpp: got %q, want C++ Header", got)
+	}
+}
+
+func TestCount_LanguageOrder(t *testing.T) {
+	mem := fstest.MapFS{
+		"a.py": {Data: []byte("x = 1\ny = 2\n")},
+		"b.rb": {Data: []byte("x = 1\ny = 2\n")},
+		"c.go": {Data: []byte("package c\n")},
+		"d.js": {Data: []byte("a();\nb();\nc();\n")},
+	}
+	for range 5 {
+		result, err := CountFS(mem, Options{})
+		if err != nil {
+			t.Fatalf("CountFS() error: %v", err)
+		}
+		var got []string
+		for _, lang := range result.Languages {
+			got = append(got, lang.Language)
+		}
+		// By code lines, largest first, then by name
+		if want := []string{"JavaScript", "Python", "Ruby", "Go"}; !slices.Equal(got, want) {
+			t.Fatalf("Languages = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestCountFS_ExcludeDirs(t *testing.T) {
+	mem := fstest.MapFS{
+		"main.go":                {Data: []byte("package main\n")},
+		".ghloc/history.json":    {Data: []byte("[]\n")},
+		".ghloc/cache/index":     {Data: []byte("x\n")},
+		".ghloc-docs/notes.md":   {Data: []byte("# Notes\n")},
+		"web/.ghloc/chart.svg":   {Data: []byte("<svg/>\n")},
+		"web/.ghloc/history.yml": {Data: []byte("a: 1\n")},
+	}
+	opt := Options{ExcludeDirs: []string{".ghloc", "web/.ghloc/"}}
+
+	result, err := CountFS(mem, opt)
+	if err != nil {
+		t.Fatalf("CountFS() error: %v", err)
+	}
+	got := fileLanguages(result)
+	if len(got) != 2 || got["main.go"] == "" || got[".ghloc-docs/notes.md"] == "" {
+		t.Errorf("expected only main.go and .ghloc-docs/notes.md, got %v", got)
+	}
+
+	e, err := ExplainFS(mem, ".ghloc/history.json", opt)
+	if err != nil || e.Counted() {
+		t.Errorf("ExplainFS(.ghloc/history.json) = %+v, %v; want excluded", e, err)
+	}
+}
+
+func TestCountFS_Symlinks(t *testing.T) {
+	mem := fstest.MapFS{
+		"src/main.go": {Data: []byte("package main\nfunc main() {}\n")},
+		"alias.go":    {Data: []byte("src/main.go"), Mode: fs.ModeSymlink},
+		"src/loop":    {Data: []byte(".."), Mode: fs.ModeSymlink},
+		"lib":         {Data: []byte("src"), Mode: fs.ModeSymlink},
+		"escape":      {Data: []byte("../outside"), Mode: fs.ModeSymlink},
+	}
+
+	result, err := CountFS(mem, Options{FollowSymlinks: true})
+	if err != nil {
+		t.Fatalf("CountFS() error: %v", err)
+	}
+	if result.TotalFiles != 1 {
+		t.Errorf("expected the linked file to be counted once, got %d: %v", result.TotalFiles, fileLanguages(result))
+	}
+	// Entries are walked in name order, so the file is reached through alias.go first.
+	if _, ok := fileLanguages(result)["alias.go"]; !ok {
+		t.Errorf("expected file reached through symlink, got %v", fileLanguages(result))
+	}
+}
+
+func TestCountTree(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	for name, content := range sampleTree {
+		if filepath.Dir(name) == ".git" {
+			continue
+		}
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755)
+		os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	}
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	git("add", "-A")
+	git("commit", "-q", "-m", "initial")
+
+	want, err := Count(dir, Options{})
+	if err != nil {
+		t.Fatalf("Count() error: %v", err)
+	}
+
+	// Uncommitted changes are not part of the tree.
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc a() {}\nfunc b() {}\nfunc c() {}\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "extra.go"), []byte("package main\n"), 0644)
+
+	got, err := CountTree(dir, "HEAD", Options{})
+	if err != nil {
+		t.Fatalf("CountTree() error: %v", err)
+	}
+	if !maps.Equal(fileLanguages(got), fileLanguages(want)) {
+		t.Errorf("CountTree files = %v, want %v", fileLanguages(got), fileLanguages(want))
+	}
+	if got.TotalCode != want.TotalCode {
+		t.Errorf("CountTree TotalCode = %d, want %d", got.TotalCode, want.TotalCode)
+	}
+
+	if _, err := CountTree(dir, "no-such-branch", Options{}); err == nil {
+		t.Error("expected error for unknown revision")
+	}
+}
+
+func TestCountArchive(t *testing.T) {
+	dir := t.TempDir()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range sampleTree {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755)
+		os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		tw.WriteHeader(&tar.Header{Name: "repo-main/" + name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+	archivePath := filepath.Join(t.TempDir(), "repo.tar.gz")
+	os.WriteFile(archivePath, buf.Bytes(), 0644)
+
+	want, err := Count(dir, Options{})
+	if err != nil {
+		t.Fatalf("Count() error: %v", err)
+	}
+	got, err := CountArchive(archivePath, Options{})
+	if err != nil {
+		t.Fatalf("CountArchive() error: %v", err)
+	}
+	if !maps.Equal(fileLanguages(got), fileLanguages(want)) {
+		t.Errorf("CountArchive files = %v, want %v", fileLanguages(got), fileLanguages(want))
+	}
+	if got.TotalCode != want.TotalCode || got.TotalULOC != want.TotalULOC {
+		t.Errorf("CountArchive totals = %d code/%d uloc, want %d/%d", got.TotalCode, got.TotalULOC, want.TotalCode, want.TotalULOC)
+	}
+	if !slices.Equal(got.Files, want.Files) {
+		t.Errorf("CountArchive files = %+v, want the directory walk's %+v", got.Files, want.Files)
+	}
+	for _, f := range want.Files {
+		if e, err := ExplainArchive(archivePath, f.Path, Options{}); err != nil || e.Stats != f {
+			t.Errorf("ExplainArchive(%s) = %+v, %v, want stats %+v", f.Path, e, err, f)
+		}
+	}
+
+	// Members over the size cap are skipped
+	got, err = CountArchive(archivePath, Options{MaxFileSize: 30})
+	if err != nil {
+		t.Fatalf("CountArchive() error: %v", err)
+	}
+	if _, ok := fileLanguages(got)["main.go"]; ok {
+		t.Errorf("main.go exceeds the size cap and should be skipped, got %v", fileLanguages(got))
+	}
+	if _, ok := fileLanguages(got)["main_test.go"]; !ok {
+		t.Errorf("main_test.go is under the size cap, got %v", fileLanguages(got))
+	}
+}
@@ -0,0 +1,311 @@
+package counter
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/boyter/scc/v3/processor"
+)
+
+// extCounts counts the lowercase file extensions present in a directory.
+type extCounts map[string]int
+
+// has reports whether any of the extensions are present.
+func (e extCounts) has(exts ...string) bool {
+	for _, ext := range exts {
+		if e[ext] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// languageResolver picks a language for each file, consulting per-path
+// overrides first, then content and sibling-file heuristics, then scc.
+type languageResolver struct {
+	fsys       fs.FS
+	overrides  []pathLanguage // config overrides, most specific first
+	attributes []pathLanguage // .gitattributes rules; the last match wins
+	siblings   map[string]extCounts
+}
+
+// pathLanguage assigns a language to paths matching a glob.
+type pathLanguage struct {
+	pattern  string
+	language string
+}
+
+// newLanguageResolver validates config overrides and loads linguist-language
+// attributes from the .gitattributes file at the root of fsys.
+func newLanguageResolver(fsys fs.FS, overrides map[string]string) (*languageResolver, error) {
+	r := &languageResolver{fsys: fsys, siblings: make(map[string]extCounts)}
+
+	for pattern, lang := range overrides {
+		canonical, ok := canonicalLanguage(lang)
+		if !ok {
+			return nil, fmt.Errorf("override %q: unknown language %q", pattern, lang)
+		}
+		r.overrides = append(r.overrides, pathLanguage{pattern: pattern, language: canonical})
+	}
+	// Longer patterns are more specific; sort by name for a stable tie-break.
+	slices.SortFunc(r.overrides, func(a, b pathLanguage) int {
+		if c := cmp.Compare(len(b.pattern), len(a.pattern)); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.pattern, b.pattern)
+	})
+
+	attrs, err := loadLinguistAttributes(fsys, ".gitattributes")
+	if err != nil {
+		return nil, err
+	}
+	r.attributes = attrs
+	return r, nil
+}
+
+// Detection sources, naming what decided a file's language in Explain output
+// and debug logs.
+const (
+	SourceOverride      = "override"      // a LanguageOverrides pattern
+	SourceGitattributes = "gitattributes" // a linguist-language attribute
+	SourceShebang       = "shebang"
+	SourceModeline      = "modeline"
+	SourceHeuristic     = "heuristic" // file contents or neighbouring files
+	SourceExtension     = "extension" // the only language scc knows for the name
+	SourceKeywords      = "keywords"  // scc's keyword-based guess among several
+)
+
+// resolve returns the language for the file at rel given the candidates scc
+// detected from its name, and the source that decided it. It returns "" for
+// extensionless files whose language cannot be identified.
+func (r *languageResolver) resolve(rel string, languages []string, content []byte) (language, source string) {
+	for _, o := range r.overrides {
+		if matchGlob(o.pattern, rel) {
+			return o.language, SourceOverride
+		}
+	}
+	for i := len(r.attributes) - 1; i >= 0; i-- {
+		if matchGlob(r.attributes[i].pattern, rel) {
+			return r.attributes[i].language, SourceGitattributes
+		}
+	}
+
+	if len(languages) == 1 && languages[0] == processor.SheBang {
+		return scriptLanguage(content)
+	}
+
+	var siblings extCounts
+	if usesSiblings(rel) {
+		siblings = r.siblingExts(path.Dir(rel))
+	}
+	return determineLanguage(path.Base(rel), languages, content, siblings)
+}
+
+// siblingExts returns the extension counts of dir, reading it at most once.
+func (r *languageResolver) siblingExts(dir string) extCounts {
+	if counts, ok := r.siblings[dir]; ok {
+		return counts
+	}
+	counts := make(extCounts)
+	entries, _ := fs.ReadDir(r.fsys, dir)
+	for _, e := range entries {
+		if !e.IsDir() {
+			counts[strings.ToLower(path.Ext(e.Name()))]++
+		}
+	}
+	r.siblings[dir] = counts
+	return counts
+}
+
+// heuristic picks a language from a file's content and, when siblings is
+// set, the extensions of the other files in its directory.
+type heuristic struct {
+	resolve  func(content []byte, siblings extCounts) string
+	siblings bool
+}
+
+// ambiguityHeuristics resolve extensions that scc maps to several languages, or
+// that it maps to a single language too eagerly (.h is always "C Header").
+// A heuristic returns "" to defer to scc's keyword-based guess.
+var ambiguityHeuristics = map[string]heuristic{
+	".h":    {headerLanguage, true},
+	".m":    {objectiveCOrMATLAB, true},
+	".v":    {verilogCoqOrV, true},
+	".tex":  {texOrLaTeX, true},
+	".tt":   {templateToolkitOrTreetop, false},
+	".heex": {heexOrLiveView, false},
+	".json": {cloudFormationOr("JSON", "CloudFormation (JSON)"), false},
+	".yaml": {cloudFormationOr("YAML", "CloudFormation (YAML)"), false},
+	".yml":  {cloudFormationOr("YAML", "CloudFormation (YAML)"), false},
+}
+
+// usesSiblings reports whether detecting the language of rel looks at the
+// other files in its directory, so that adding or removing one can change it.
+func usesSiblings(rel string) bool {
+	return ambiguityHeuristics[strings.ToLower(path.Ext(rel))].siblings
+}
+
+// determineLanguage tries to pick the best language when multiple are possible,
+// and reports the source that decided it.
+func determineLanguage(filename string, languages []string, content []byte, siblings extCounts) (language, source string) {
+	// Pass a reasonable chunk of content for keyword analysis
+	sample := content
+	if len(sample) > 20000 {
+		sample = sample[:20000]
+	}
+
+	// Use extension-specific content and sibling heuristics
+	ext := strings.ToLower(filepath.Ext(filename))
+	if h, ok := ambiguityHeuristics[ext]; ok {
+		if lang := h.resolve(sample, siblings); lang != "" {
+			return lang, SourceHeuristic
+		}
+	}
+
+	if len(languages) == 1 {
+		return languages[0], SourceExtension
+	}
+
+	// For other ambiguous cases, try scc's built-in determination
+	return processor.DetermineLanguage(filename, languages[0], languages, sample), SourceKeywords
+}
+
+var (
+	cppMarkers     = regexp.MustCompile(`(?m)^\s*(class|namespace|template\s*<)|std::|\b(public|private|protected):|#include <(iostream|string|vector|memory|map)>`)
+	objcMarkers    = regexp.MustCompile(`(?m)^\s*(@interface|@implementation|@protocol|@end|@property|#import)\b`)
+	matlabMarkers  = regexp.MustCompile(`(?m)^\s*(function\s.*=|function\s+\w+\s*\(|%|end\s*$|disp\(|fprintf\(|zeros\(|plot\()`)
+	verilogMarkers = regexp.MustCompile(`(?m)\bendmodule\b|\balways\s*@|^\s*(assign|input|output|reg|wire)\b`)
+	coqMarkers     = regexp.MustCompile(`(?m)^\s*(Theorem|Lemma|Proof\.|Qed\.|Require\s+Import|Inductive|Fixpoint)\b`)
+	vlangMarkers   = regexp.MustCompile(`(?m)^\s*(fn\s+\w+\s*\(|pub\s+fn|import\s+\w+$|struct\s+\w+\s*\{)`)
+	latexMarkers   = regexp.MustCompile(`\\(documentclass|usepackage|begin\{document\}|section\{)`)
+	treetopMarkers = regexp.MustCompile(`(?m)^\s*(grammar|rule)\s+\w+`)
+)
+
+// headerLanguage distinguishes C, C++ and Objective-C headers.
+func headerLanguage(content []byte, siblings extCounts) string {
+	switch {
+	case objcMarkers.Match(content):
+		return "Objective C"
+	case cppMarkers.Match(content):
+		return "C++ Header"
+	}
+
+	// No decisive content: follow the implementation files next to the header.
+	cpp := siblings.has(".cpp", ".cc", ".cxx", ".c++", ".hpp", ".hh", ".hxx")
+	objc := siblings.has(".m", ".mm")
+	c := siblings.has(".c")
+	switch {
+	case cpp && !c && !objc:
+		return "C++ Header"
+	case objc && !c && !cpp:
+		return "Objective C"
+	}
+	return "C Header"
+}
+
+// objectiveCOrMATLAB distinguishes Objective-C implementation files from MATLAB scripts.
+func objectiveCOrMATLAB(content []byte, siblings extCounts) string {
+	switch {
+	case objcMarkers.Match(content), bytes.Contains(content, []byte("#include")):
+		return "Objective C"
+	case matlabMarkers.Match(content):
+		return "MATLAB"
+	case siblings.has(".h", ".mm"):
+		return "Objective C"
+	case siblings.has(".mat", ".fig", ".mlx", ".slx"):
+		return "MATLAB"
+	}
+	return ""
+}
+
+// verilogCoqOrV distinguishes Verilog, Coq proofs and V sources.
+func verilogCoqOrV(content []byte, siblings extCounts) string {
+	switch {
+	case verilogMarkers.Match(content):
+		return "Verilog"
+	case coqMarkers.Match(content):
+		return "Coq"
+	case vlangMarkers.Match(content):
+		return "V"
+	case siblings.has(".sv", ".svh", ".vh"):
+		return "Verilog"
+	case siblings.has(".vo", ".glob"):
+		return "Coq"
+	}
+	return ""
+}
+
+// texOrLaTeX treats documents using LaTeX macros as LaTeX and the rest as plain TeX.
+func texOrLaTeX(content []byte, siblings extCounts) string {
+	if latexMarkers.Match(content) || siblings.has(".sty", ".cls", ".bib") {
+		return "LaTeX"
+	}
+	return "TeX"
+}
+
+// templateToolkitOrTreetop distinguishes Treetop grammars from Template Toolkit templates.
+func templateToolkitOrTreetop(content []byte, siblings extCounts) string {
+	switch {
+	case bytes.Contains(content, []byte("[%")):
+		return "TemplateToolkit"
+	case treetopMarkers.Match(content):
+		return "Treetop"
+	}
+	return ""
+}
+
+// heexOrLiveView counts .heex templates as HEEx; LiveView code lives in .ex files.
+func heexOrLiveView(content []byte, siblings extCounts) string {
+	return "HEEx"
+}
+
+// cloudFormationOr returns a heuristic that picks the CloudFormation dialect
+// only for documents that declare a template format version or AWS resources.
+func cloudFormationOr(plain, cloudFormation string) func([]byte, extCounts) string {
+	return func(content []byte, _ extCounts) string {
+		if bytes.Contains(content, []byte("AWSTemplateFormatVersion")) || bytes.Contains(content, []byte("AWS::")) {
+			return cloudFormation
+		}
+		return plain
+	}
+}
+
+// languageIndex maps normalized language names to scc's canonical names.
+var languageIndex map[string]string
+
+// buildLanguageIndex records every language scc knows. Called once after
+// processor.ProcessConstants.
+func buildLanguageIndex() {
+	processor.LanguageFeaturesMutex.Lock()
+	defer processor.LanguageFeaturesMutex.Unlock()
+
+	languageIndex = make(map[string]string, len(processor.LanguageFeatures))
+	for name := range processor.LanguageFeatures {
+		languageIndex[normalizeLanguageName(name)] = name
+	}
+}
+
+// canonicalLanguage resolves a user-supplied language name, such as a Linguist
+// name from .gitattributes ("Objective-C", "c++"), to scc's spelling.
+func canonicalLanguage(name string) (string, bool) {
+	canonical, ok := languageIndex[normalizeLanguageName(name)]
+	return canonical, ok
+}
+
+// normalizeLanguageName lowercases name and drops spaces, hyphens and underscores.
+func normalizeLanguageName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '_':
+			return -1
+		}
+		return r
+	}, strings.ToLower(name))
+}
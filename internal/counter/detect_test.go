@@ -0,0 +1,179 @@
+package counter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/boyter/scc/v3/processor"
+)
+
+func TestDetermineLanguage_AmbiguousExtensions(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+		siblings extCounts
+		want     string
+	}{
+		// .h: C, C++ or Objective-C header
+		{"h plain C", "util.h", "#ifndef UTIL_H\nint add(int a, int b);\n#endif\n", nil, "C Header"},
+		{"h C++ class", "widget.h", "#pragma once\nclass Widget {\npublic:\n  int x;\n};\n", nil, "C++ Header"},
+		{"h C++ namespace", "ns.h", "namespace foo {\nint bar();\n}\n", nil, "C++ Header"},
+		{"h Objective-C", "View.h", "#import <UIKit/UIKit.h>\n@interface View : UIView\n@end\n", nil, "Objective C"},
+		{"h beside cpp", "api.h", "int api();\n", extCounts{".cpp": 3, ".h": 2}, "C++ Header"},
+		{"h beside m", "api.h", "int api();\n", extCounts{".m": 1}, "Objective C"},
+		{"h beside c and cpp", "api.h", "int api();\n", extCounts{".c": 1, ".cpp": 1}, "C Header"},
+
+		// .m: Objective-C or MATLAB
+		{"m Objective-C", "View.m", "#import \"View.h\"\n@implementation View\n@end\n", nil, "Objective C"},
+		{"m MATLAB function", "solve.m", "function x = solve(A, b)\n% solve a system\nx = A \\ b;\nend\n", nil, "MATLAB"},
+		{"m MATLAB script", "plotit.m", "% plot data\ny = zeros(10);\nplot(y)\n", nil, "MATLAB"},
+		{"m beside mat", "run.m", "x = 1;\n", extCounts{".mat": 1}, "MATLAB"},
+
+		// .v: Verilog, Coq or V
+		{"v Verilog", "adder.v", "module adder(input a, input b, output s);\n  assign s = a ^ b;\nendmodule\n", nil, "Verilog"},
+		{"v Coq", "proof.v", "Require Import Arith.\nTheorem t : 1 = 1.\nProof. reflexivity. Qed.\n", nil, "Coq"},
+		{"v V", "main.v", "module main\n\nfn main() {\n\tprintln('hi')\n}\n", nil, "V"},
+
+		// .tex: TeX or LaTeX
+		{"tex LaTeX", "paper.tex", "\\documentclass{article}\n\\begin{document}\nHi\n\\end{document}\n", nil, "LaTeX"},
+		{"tex plain", "macros.tex", "\\def\\foo{bar}\n\\bye\n", nil, "TeX"},
+
+		// .tt: Template Toolkit or Treetop
+		{"tt Template Toolkit", "page.tt", "<p>[% name %]</p>\n", nil, "TemplateToolkit"},
+		{"tt Treetop", "arith.tt", "grammar Arithmetic\n  rule number\n    [0-9]+\n  end\nend\n", nil, "Treetop"},
+
+		// .heex: HEEx or Phoenix LiveView
+		{"heex", "index.html.heex", "<div><%= @title %></div>\n", nil, "HEEx"},
+
+		// .json/.yaml/.yml: plain or CloudFormation
+		{"json plain", "package.json", "{\"name\": \"x\"}\n", nil, "JSON"},
+		{"json CloudFormation", "stack.json", "{\"AWSTemplateFormatVersion\": \"2010-09-09\"}\n", nil, "CloudFormation (JSON)"},
+		{"yaml plain", "config.yaml", "a: 1\n", nil, "YAML"},
+		{"yaml CloudFormation", "stack.yaml", "Resources:\n  Bucket:\n    Type: AWS::S3::Bucket\n", nil, "CloudFormation (YAML)"},
+		{"yml plain", "ci.yml", "on: push\n", nil, "YAML"},
+		{"yml CloudFormation", "stack.yml", "AWSTemplateFormatVersion: '2010-09-09'\n", nil, "CloudFormation (YAML)"},
+	}
+
+	initProcessor()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			languages, _ := processor.DetectLanguage(tt.filename)
+			got, _ := determineLanguage(tt.filename, languages, []byte(tt.content), tt.siblings)
+			if got != tt.want {
+				t.Errorf("determineLanguage(%q) = %q, want %q", tt.filename, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestAmbiguityHeuristics_CoverScc fails when scc maps an extension to several
+// languages and no heuristic handles it, e.g. after upgrading scc.
+func TestAmbiguityHeuristics_CoverScc(t *testing.T) {
+	initProcessor()
+
+	for ext, languages := range processor.ExtensionToLanguage {
+		if len(languages) < 2 {
+			continue
+		}
+		if _, ok := ambiguityHeuristics["."+ext]; !ok {
+			t.Errorf("extension .%s is ambiguous in scc (%s) but has no heuristic", ext, strings.Join(languages, ", "))
+		}
+	}
+	for ext, h := range ambiguityHeuristics {
+		lang := h.resolve(nil, nil)
+		if _, ok := canonicalLanguage(lang); lang != "" && !ok {
+			t.Errorf("heuristic for %s returns unknown language %q", ext, lang)
+		}
+	}
+}
+
+func TestCanonicalLanguage(t *testing.T) {
+	initProcessor()
+
+	tests := map[string]string{
+		"Objective-C": "Objective C",
+		"c++":         "C++",
+		"matlab":      "MATLAB",
+		"C++ Header":  "C++ Header",
+	}
+	for in, want := range tests {
+		if got, ok := canonicalLanguage(in); !ok || got != want {
+			t.Errorf("canonicalLanguage(%q) = %q, %v; want %q", in, got, ok, want)
+		}
+	}
+	if _, ok := canonicalLanguage("Klingon"); ok {
+		t.Error("expected unknown language")
+	}
+}
+
+func TestCount_SiblingContext(t *testing.T) {
+	dir := t.TempDir()
+
+	os.MkdirAll(filepath.Join(dir, "cpp"), 0755)
+	os.MkdirAll(filepath.Join(dir, "c"), 0755)
+	header := "int api(void);\n"
+	os.WriteFile(filepath.Join(dir, "cpp", "api.h"), []byte(header), 0644)
+	os.WriteFile(filepath.Join(dir, "cpp", "api.cpp"), []byte("int api() { return 1; }\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "c", "api.h"), []byte(header), 0644)
+	os.WriteFile(filepath.Join(dir, "c", "api.c"), []byte("int api(void) { return 1; }\n"), 0644)
+
+	result, err := Count(dir, Options{})
+	if err != nil {
+		t.Fatalf("Count() error: %v", err)
+	}
+
+	got := fileLanguages(result)
+	if got["cpp/api.h"] != "C++ Header" {
+		t.Errorf("cpp/api.h: got %q, want C++ Header", got["cpp/api.h"])
+	}
+	if got["c/api.h"] != "C Header" {
+		t.Errorf("c/api.h: got %q, want C Header", got["c/api.h"])
+	}
+}
+
+func TestCount_LanguageOverrides(t *testing.T) {
+	dir := t.TempDir()
+
+	os.MkdirAll(filepath.Join(dir, "matlab"), 0755)
+	os.MkdirAll(filepath.Join(dir, "include"), 0755)
+	os.WriteFile(filepath.Join(dir, "matlab", "run.m"), []byte("x = 1;\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "include", "api.h"), []byte("int api(void);\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "gen.h"), []byte("int gen(void);\n"), 0644)
+	os.WriteFile(filepath.Join(dir, ".gitattributes"), []byte(
+		"# comment\n*.h linguist-language=C++\ngen.h linguist-language=Objective-C\n*.txt linguist-language=Klingon\n"), 0644)
+
+	result, err := Count(dir, Options{LanguageOverrides: map[string]string{
+		"matlab/**": "MATLAB",
+	}})
+	if err != nil {
+		t.Fatalf("Count() error: %v", err)
+	}
+
+	got := fileLanguages(result)
+	want := map[string]string{
+		"matlab/run.m":  "MATLAB",
+		"include/api.h": "C++",
+		"gen.h":         "Objective C", // later gitattributes lines win
+	}
+	for path, lang := range want {
+		if got[path] != lang {
+			t.Errorf("%s: got %q, want %q", path, got[path], lang)
+		}
+	}
+
+	if _, err := Count(dir, Options{LanguageOverrides: map[string]string{"*.m": "Klingon"}}); err == nil {
+		t.Error("expected error for override with unknown language")
+	}
+}
+
+func fileLanguages(result *LOCResult) map[string]string {
+	langs := make(map[string]string)
+	for _, f := range result.Files {
+		langs[f.Path] = f.Language
+	}
+	return langs
+}
@@ -0,0 +1,116 @@
+package counter
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/rjwalters/ghloc/internal/archive"
+)
+
+// Explanation describes how Count treats a single file: which languages its
+// name suggests, which one was chosen and why, and whether it is counted.
+type Explanation struct {
+	Path       string
+	Candidates []string   // languages scc associates with the file name
+	Detected   string     // language chosen by detection, before Options.Languages
+	Source     string     // what chose Detected: SourceOverride, SourceShebang, ...
+	Cached     bool       // whether the outcome came from Options.Cache
+	Language   string     // reported language, after renames and groups
+	Category   string     // category of the detected language
+	Skipped    SkipReason // why the file is not counted, if it is skipped
+	Err        error      // the read error, for SkipUnreadable
+	Excluded   string     // why configuration leaves the file out, if it does
+	Stats      FileStats  // line counts of a counted file
+	InTotals   bool       // whether the file contributes to the result totals
+	Submodule  string     // path of the submodule containing the file, if any
+}
+
+// Counted reports whether the file contributes to a count.
+func (e *Explanation) Counted() bool {
+	return e.Skipped == "" && e.Excluded == ""
+}
+
+// Explain reports how Count(dir, opt) would treat the file at name, a path
+// relative to dir. The cache is neither consulted nor updated.
+func Explain(dir, name string, opt Options) (*Explanation, error) {
+	rel := filepath.ToSlash(filepath.Clean(name))
+	return explain(os.DirFS(dir), dir, rel, opt)
+}
+
+// ExplainFS reports how CountFS(fsys, opt) would treat the file at name.
+func ExplainFS(fsys fs.FS, name string, opt Options) (*Explanation, error) {
+	return explain(fsys, "", name, opt)
+}
+
+// ExplainArchive reports how CountArchive(path, opt) would treat the file
+// at name, a path relative to the archive's root.
+func ExplainArchive(path, name string, opt Options) (*Explanation, error) {
+	fsys, err := archive.Open(path, opt.maxFileSize())
+	if err != nil {
+		return nil, err
+	}
+	defer fsys.Close()
+	return ExplainFS(fsys, filepath.ToSlash(filepath.Clean(name)), opt)
+}
+
+func explain(fsys fs.FS, dir, rel string, opt Options) (*Explanation, error) {
+	opt.Cache = nil
+	if !fs.ValidPath(rel) || rel == "." {
+		return nil, fmt.Errorf("explain %s: not a file inside the tree", rel)
+	}
+
+	initProcessor()
+	countMu.Lock()
+	defer countMu.Unlock()
+
+	c, err := newCounting(fsys, dir, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	linfo, err := lstat(fsys, rel)
+	if err != nil {
+		return nil, fmt.Errorf("explain: %w", err)
+	}
+	e := &Explanation{Path: rel}
+	if slices.Contains(strings.Split(rel, "/"), ".git") {
+		e.Excluded = "inside a .git directory"
+		return e, nil
+	}
+	if c.excludedDir(rel) {
+		e.Excluded = "inside an excluded directory"
+		return e, nil
+	}
+	if sub, ok := submoduleFor(c.submodules, rel); ok && opt.Submodules == SubmodulesExclude {
+		e.Submodule = sub.path
+		e.Excluded = "submodule " + sub.path + " is excluded"
+		return e, nil
+	}
+	if linfo.Mode()&fs.ModeSymlink != 0 && !opt.FollowSymlinks {
+		e.Excluded = "symlink, and symlinks are not followed"
+		return e, nil
+	}
+	info, err := fs.Stat(fsys, rel)
+	if err != nil {
+		return nil, fmt.Errorf("explain: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("explain %s: not a regular file", rel)
+	}
+
+	e, _ = c.classify(rel, info.Size())
+	return e, nil
+}
+
+// lstat describes the file at name without following a final symlink, when
+// fsys supports that.
+func lstat(fsys fs.FS, name string) (fs.FileInfo, error) {
+	if linkFS, ok := fsys.(fs.ReadLinkFS); ok {
+		return linkFS.Lstat(name)
+	}
+	return fs.Stat(fsys, name)
+}
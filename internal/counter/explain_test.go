@@ -0,0 +1,121 @@
+package counter
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestExplainFS(t *testing.T) {
+	mem := fstest.MapFS{
+		"main.go":          {Data: []byte("package main\nfunc main() {}\n")},
+		"build":            {Data: []byte("#!/usr/bin/env python3\nprint('hi')\n")},
+		"gen/api.pb.go":    {Data: []byte("package gen\n")},
+		"docs/guide.md":    {Data: []byte("# Guide\n")},
+		"notes.unknownx":   {Data: []byte("hello\n")},
+		"huge.go":          {Data: bytes.Repeat([]byte("var x = 1\n"), 200)},
+		".git/config":      {Data: []byte("[core]\n")},
+		"vendor/lib/a.go":  {Data: []byte("package lib\n")},
+		".gitmodules":      {Data: []byte("[submodule \"lib\"]\n\tpath = vendor/lib\n\turl = x\n")},
+		"src/include/x.h":  {Data: []byte("#include <vector>\nclass A {};\n")},
+		"src/include/x.cc": {Data: []byte("int main() {}\n")},
+	}
+	opts := Options{
+		MaxFileSize:       1500,
+		LanguageOverrides: map[string]string{"gen/**": "Protocol Buffers"},
+		Languages:         LanguageRules{Exclude: []string{"Markdown"}},
+		Submodules:        SubmodulesExclude,
+	}
+
+	tests := []struct {
+		path     string
+		language string
+		source   string
+		skipped  SkipReason
+		excluded bool
+	}{
+		{path: "main.go", language: "Go", source: SourceExtension},
+		{path: "build", language: "Python", source: SourceShebang},
+		{path: "gen/api.pb.go", language: "Protocol Buffers", source: SourceOverride},
+		{path: "src/include/x.h", language: "C++ Header", source: SourceHeuristic},
+		{path: "docs/guide.md", source: SourceExtension, excluded: true},
+		{path: "notes.unknownx", skipped: SkipUnknownLanguage},
+		{path: "huge.go", skipped: SkipTooLarge},
+		{path: ".git/config", excluded: true},
+		{path: "vendor/lib/a.go", excluded: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			e, err := ExplainFS(mem, tt.path, opts)
+			if err != nil {
+				t.Fatalf("ExplainFS() error: %v", err)
+			}
+			if e.Language != tt.language || e.Skipped != tt.skipped || (e.Excluded != "") != tt.excluded {
+				t.Errorf("got language %q, skipped %q, excluded %q; want %q, %q, %v",
+					e.Language, e.Skipped, e.Excluded, tt.language, tt.skipped, tt.excluded)
+			}
+			if tt.source != "" && e.Source != tt.source {
+				t.Errorf("Source = %q, want %q", e.Source, tt.source)
+			}
+			if e.Counted() != (tt.language != "") {
+				t.Errorf("Counted() = %v", e.Counted())
+			}
+		})
+	}
+
+	if _, err := ExplainFS(mem, "missing.go", opts); err == nil {
+		t.Error("expected error for a missing file")
+	}
+	if _, err := ExplainFS(mem, "src", opts); err == nil {
+		t.Error("expected error for a directory")
+	}
+}
+
+func TestExplain_MatchesCount(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range sampleTree {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755)
+		os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	}
+
+	result, err := Count(dir, Options{})
+	if err != nil {
+		t.Fatalf("Count() error: %v", err)
+	}
+	for _, f := range result.Files {
+		e, err := Explain(dir, f.Path, Options{})
+		if err != nil {
+			t.Fatalf("Explain(%s) error: %v", f.Path, err)
+		}
+		if !e.Counted() || e.Stats != f {
+			t.Errorf("Explain(%s) = %+v, want stats %+v", f.Path, e, f)
+		}
+	}
+}
+
+func TestCount_Logger(t *testing.T) {
+	mem := fstest.MapFS{
+		"main.go":       {Data: []byte("package main\n")},
+		"data.unknownx": {Data: []byte("hello\n")},
+	}
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	if _, err := CountFS(mem, Options{Logger: logger}); err != nil {
+		t.Fatalf("CountFS() error: %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		`msg="counted file" path=main.go language=Go detected=Go source=extension`,
+		`msg="skipped file" path=data.unknownx reason="unknown language"`,
+		`level=INFO msg="count finished" files=1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log missing %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package counter
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// loadLinguistAttributes reads linguist-language overrides from the .gitattributes
+// file name in fsys, e.g. "*.h linguist-language=C++". Unknown languages are
+// ignored, as GitHub Linguist knows languages scc does not. A missing file
+// yields no rules.
+func loadLinguistAttributes(fsys fs.FS, name string) ([]pathLanguage, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read gitattributes: %w", err)
+	}
+
+	var rules []pathLanguage
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		pattern := strings.TrimPrefix(fields[0], "/")
+		if strings.HasSuffix(pattern, "/") {
+			pattern += "**"
+		}
+		for _, attr := range fields[1:] {
+			value, ok := strings.CutPrefix(attr, "linguist-language=")
+			if !ok {
+				continue
+			}
+			if lang, known := canonicalLanguage(value); known {
+				rules = append(rules, pathLanguage{pattern: pattern, language: lang})
+			}
+		}
+	}
+	return rules, scanner.Err()
+}
@@ -0,0 +1,73 @@
+package counter
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// submodule is a git submodule declared in .gitmodules.
+type submodule struct {
+	name string
+	path string // slash-separated, relative to the repository root
+}
+
+// loadGitmodules parses the submodule names and paths from the .gitmodules
+// file name in fsys. A missing file yields no submodules.
+func loadGitmodules(fsys fs.FS, name string) ([]submodule, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read gitmodules: %w", err)
+	}
+
+	var subs []submodule
+	current := -1 // index into subs of the section being parsed
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+			continue
+		case strings.HasPrefix(line, "[submodule"):
+			name := strings.TrimSuffix(strings.TrimPrefix(line, "[submodule"), "]")
+			subs = append(subs, submodule{name: strings.Trim(strings.TrimSpace(name), `"`)})
+			current = len(subs) - 1
+		case strings.HasPrefix(line, "["):
+			current = -1 // some other section
+		case current >= 0:
+			key, value, ok := strings.Cut(line, "=")
+			if ok && strings.TrimSpace(key) == "path" {
+				subs[current].path = path.Clean(strings.Trim(strings.TrimSpace(value), `"`))
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("parse gitmodules: %w", err)
+	}
+
+	// Drop entries without a path
+	valid := subs[:0]
+	for _, s := range subs {
+		if s.path != "" && s.path != "." {
+			valid = append(valid, s)
+		}
+	}
+	return valid, nil
+}
+
+// submoduleFor returns the submodule containing the relative path rel, if any.
+func submoduleFor(subs []submodule, rel string) (submodule, bool) {
+	for _, s := range subs {
+		if rel == s.path || strings.HasPrefix(rel, s.path+"/") {
+			return s, true
+		}
+	}
+	return submodule{}, false
+}
@@ -0,0 +1,51 @@
+package counter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadGitmodules(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, ".gitmodules"), []byte(`# comment
+[submodule "a"]
+	path = libs/a
+	url = https://example.com/a.git
+[core]
+	path = ignored
+[submodule "b"]
+	url = https://example.com/b.git
+	path = "libs/b/"
+[submodule "nopath"]
+	url = https://example.com/c.git
+`), 0644)
+
+	subs, err := loadGitmodules(os.DirFS(dir), ".gitmodules")
+	if err != nil {
+		t.Fatalf("loadGitmodules() error: %v", err)
+	}
+	want := []submodule{{name: "a", path: "libs/a"}, {name: "b", path: "libs/b"}}
+	if len(subs) != len(want) {
+		t.Fatalf("expected %d submodules, got %+v", len(want), subs)
+	}
+	for i := range want {
+		if subs[i] != want[i] {
+			t.Errorf("submodule %d: got %+v, want %+v", i, subs[i], want[i])
+		}
+	}
+
+	if _, ok := submoduleFor(subs, "libs/a/x.go"); !ok {
+		t.Error("libs/a/x.go should be in submodule a")
+	}
+	if _, ok := submoduleFor(subs, "libs/ab/x.go"); ok {
+		t.Error("libs/ab/x.go should not match submodule libs/a")
+	}
+}
+
+func TestLoadGitmodules_NoFile(t *testing.T) {
+	subs, err := loadGitmodules(os.DirFS(t.TempDir()), ".gitmodules")
+	if err != nil || subs != nil {
+		t.Errorf("loadGitmodules() = %v, %v; want nil, nil", subs, err)
+	}
+}
@@ -0,0 +1,50 @@
+package counter
+
+import (
+	"path"
+	"strings"
+)
+
+// matchGlob reports whether the slash-separated relative path p matches pattern.
+// Patterns without a slash match against the file name only. Patterns with a
+// slash match the whole path, where a "**" segment matches zero or more
+// directories. Other segments use path.Match syntax.
+func matchGlob(pattern, p string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(p))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(p, "/"))
+}
+
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(rest, parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
+
+// matchAny reports whether p matches any of the patterns.
+func matchAny(patterns []string, p string) bool {
+	for _, pattern := range patterns {
+		if matchGlob(pattern, p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,31 @@
+package counter
+
+import "testing"
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"*_test.go", "internal/counter/counter_test.go", true},
+		{"*_test.go", "internal/counter/counter.go", false},
+		{"*.spec.ts", "src/app/app.spec.ts", true},
+		{"test_*.py", "pkg/test_utils.py", true},
+		{"test_*.py", "pkg/utils_test_helper.py", false},
+		{"**/src/test/**", "src/test/java/com/example/FooTest.java", true},
+		{"**/src/test/**", "module/src/test/java/Foo.java", true},
+		{"**/src/test/**", "src/main/java/Foo.java", false},
+		{"**/tests/**", "tests/integration.rs", true},
+		{"**/tests/**", "crates/core/tests/fixtures/a.rs", true},
+		{"**/tests/**", "tests.rs", false},
+		{"docs/*.md", "docs/readme.md", true},
+		{"docs/*.md", "docs/sub/readme.md", false},
+		{"vendor/**", "vendor/a/b/c.go", true},
+	}
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
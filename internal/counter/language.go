@@ -0,0 +1,124 @@
+package counter
+
+import "slices"
+
+// Language categories used by LanguageRules to exclude or filter languages.
+const (
+	CategoryCode   = "code"
+	CategoryMarkup = "markup"
+	CategoryData   = "data"
+	CategoryProse  = "prose"
+	CategoryConfig = "config"
+)
+
+// languageCategories classifies scc language names that are not programming
+// languages. Anything not listed is CategoryCode.
+var languageCategories = map[string]string{
+	"HTML":                                  CategoryMarkup,
+	"XML":                                   CategoryMarkup,
+	"SVG":                                   CategoryMarkup,
+	"XAML":                                  CategoryMarkup,
+	"XML Schema":                            CategoryMarkup,
+	"Document Type Definition":              CategoryMarkup,
+	"FXML":                                  CategoryMarkup,
+	"Macromedia eXtensible Markup Language": CategoryMarkup,
+	"Web Services Description Language":     CategoryMarkup,
+	"nuspec":                                CategoryMarkup,
+	"JSON":                                  CategoryData,
+	"JSON5":                                 CategoryData,
+	"JSONC":                                 CategoryData,
+	"JSONL":                                 CategoryData,
+	"YAML":                                  CategoryData,
+	"TOML":                                  CategoryData,
+	"CSV":                                   CategoryData,
+	"INI":                                   CategoryData,
+	"Properties File":                       CategoryData,
+	"HEX":                                   CategoryData,
+	"Intel HEX":                             CategoryData,
+	"Patch":                                 CategoryData,
+	"Markdown":                              CategoryProse,
+	"MDX":                                   CategoryProse,
+	"Plain Text":                            CategoryProse,
+	"ReStructuredText":                      CategoryProse,
+	"AsciiDoc":                              CategoryProse,
+	"Org":                                   CategoryProse,
+	"Textile":                               CategoryProse,
+	"Creole":                                CategoryProse,
+	"TeX":                                   CategoryProse,
+	"LaTeX":                                 CategoryProse,
+	"Rich Text Format":                      CategoryProse,
+	"TaskPaper":                             CategoryProse,
+	"License":                               CategoryProse,
+	"SPDX":                                  CategoryProse,
+	"gitignore":                             CategoryConfig,
+	"ignore":                                CategoryConfig,
+	"Docker ignore":                         CategoryConfig,
+	"Xcode Config":                          CategoryConfig,
+	"Systemd":                               CategoryConfig,
+}
+
+// LanguageCategory returns the category of an scc language name.
+func LanguageCategory(language string) string {
+	if c, ok := languageCategories[language]; ok {
+		return c
+	}
+	return CategoryCode
+}
+
+// LanguageRules normalizes detected languages before they are counted.
+// Rules apply in order: rename, then group, then exclude. A file's category
+// always comes from its detected language.
+type LanguageRules struct {
+	// Rename maps a detected language name to a new name, e.g. "C Header" to "C".
+	Rename map[string]string
+	// Groups merges several languages (after renaming) under one name,
+	// e.g. "Frontend" for JavaScript, JSX and TypeScript.
+	Groups map[string][]string
+	// Exclude drops files whose detected, renamed or grouped language, or
+	// whose category (markup, data, prose, config), is listed.
+	Exclude []string
+	// CodeOnly restricts the result totals to languages in the code
+	// category. Other languages are still reported individually.
+	CodeOnly bool
+}
+
+// languageMapper applies LanguageRules with precomputed lookups.
+type languageMapper struct {
+	rules   LanguageRules
+	groupOf map[string]string
+}
+
+func newLanguageMapper(rules LanguageRules) *languageMapper {
+	m := &languageMapper{rules: rules, groupOf: make(map[string]string)}
+	for group, members := range rules.Groups {
+		for _, member := range members {
+			m.groupOf[member] = group
+		}
+	}
+	return m
+}
+
+// resolve maps a detected language to its reported name and category. It
+// reports false when the language is excluded.
+func (m *languageMapper) resolve(detected string) (name, category string, ok bool) {
+	category = LanguageCategory(detected)
+	name = detected
+	if renamed, found := m.rules.Rename[name]; found {
+		name = renamed
+	}
+	if group, found := m.groupOf[name]; found {
+		name = group
+	}
+	for _, excluded := range []string{detected, name, category} {
+		if slices.Contains(m.rules.Exclude, excluded) {
+			return "", "", false
+		}
+	}
+	return name, category, true
+}
+
+// countsTowardTotals reports whether a language in category contributes to
+// the result totals.
+func (m *languageMapper) countsTowardTotals(category string) bool {
+	return !m.rules.CodeOnly || category == CategoryCode
+}
@@ -0,0 +1,84 @@
+package counter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLanguageMapper_Resolve(t *testing.T) {
+	m := newLanguageMapper(LanguageRules{
+		Rename:  map[string]string{"C Header": "C"},
+		Groups:  map[string][]string{"Frontend": {"JavaScript", "JSX", "TypeScript"}},
+		Exclude: []string{CategoryMarkup, "Plain Text", "Legacy"},
+	})
+
+	tests := []struct {
+		detected     string
+		wantName     string
+		wantCategory string
+		wantOK       bool
+	}{
+		{"Go", "Go", CategoryCode, true},
+		{"C Header", "C", CategoryCode, true},
+		{"TypeScript", "Frontend", CategoryCode, true},
+		{"JSX", "Frontend", CategoryCode, true},
+		{"SVG", "", "", false},
+		{"HTML", "", "", false},
+		{"Plain Text", "", "", false},
+		{"Markdown", "Markdown", CategoryProse, true},
+		{"YAML", "YAML", CategoryData, true},
+	}
+	for _, tt := range tests {
+		name, category, ok := m.resolve(tt.detected)
+		if name != tt.wantName || category != tt.wantCategory || ok != tt.wantOK {
+			t.Errorf("resolve(%q) = %q, %q, %v; want %q, %q, %v",
+				tt.detected, name, category, ok, tt.wantName, tt.wantCategory, tt.wantOK)
+		}
+	}
+}
+
+func TestLanguageMapper_ExcludeRenamed(t *testing.T) {
+	m := newLanguageMapper(LanguageRules{
+		Rename:  map[string]string{"Perl": "Legacy"},
+		Exclude: []string{"Legacy"},
+	})
+	if _, _, ok := m.resolve("Perl"); ok {
+		t.Error("expected language excluded by its renamed name")
+	}
+}
+
+func TestCount_LanguageRules(t *testing.T) {
+	dir := t.TempDir()
+
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\nfunc main() {}\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("# Title\n\nSome prose.\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "config.yaml"), []byte("a: 1\nb: 2\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "logo.svg"), []byte("<svg></svg>\n"), 0644)
+
+	result, err := Count(dir, Options{Languages: LanguageRules{
+		Exclude:  []string{CategoryMarkup},
+		CodeOnly: true,
+	}})
+	if err != nil {
+		t.Fatalf("Count() error: %v", err)
+	}
+
+	// SVG is excluded entirely; Markdown and YAML are reported but not totaled
+	langs := make(map[string]LanguageStats)
+	for _, l := range result.Languages {
+		langs[l.Language] = l
+	}
+	if _, ok := langs["SVG"]; ok {
+		t.Error("SVG should be excluded")
+	}
+	if langs["Markdown"].Category != CategoryProse || langs["YAML"].Category != CategoryData {
+		t.Errorf("unexpected categories: %+v", langs)
+	}
+	if result.TotalCode != 2 || result.TotalFiles != 1 {
+		t.Errorf("code-only totals: got code=%d files=%d, want 2/1", result.TotalCode, result.TotalFiles)
+	}
+	if len(result.Files) != 3 {
+		t.Errorf("expected 3 file records, got %d", len(result.Files))
+	}
+}
@@ -0,0 +1,129 @@
+package counter
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// SubmoduleMode controls how files inside git submodules are counted.
+type SubmoduleMode string
+
+const (
+	// SubmodulesInclude counts submodule files like any other file and also
+	// reports per-submodule totals. This is the default.
+	SubmodulesInclude SubmoduleMode = "include"
+	// SubmodulesExclude skips submodule directories entirely.
+	SubmodulesExclude SubmoduleMode = "exclude"
+	// SubmodulesSeparate reports submodule files only in their submodule's
+	// entry, keeping them out of the repository totals.
+	SubmodulesSeparate SubmoduleMode = "separate"
+)
+
+// ParseSubmoduleMode validates a submodule mode name; "" means SubmodulesInclude.
+func ParseSubmoduleMode(s string) (SubmoduleMode, error) {
+	switch m := SubmoduleMode(s); m {
+	case "":
+		return SubmodulesInclude, nil
+	case SubmodulesInclude, SubmodulesExclude, SubmodulesSeparate:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown submodule mode %q (want include, exclude or separate)", s)
+}
+
+// Options configures how Count classifies and attributes files.
+type Options struct {
+	// TestPatterns are globs identifying test files (see matchGlob for syntax).
+	// Nil means DefaultTestPatterns; an empty non-nil slice disables test detection.
+	TestPatterns []string
+
+	// LanguageOverrides forces the language of files matching a glob, taking
+	// precedence over .gitattributes linguist-language and detection. When
+	// several patterns match, the longest wins.
+	LanguageOverrides map[string]string
+
+	// Languages renames, groups and excludes detected languages.
+	Languages LanguageRules
+
+	// FollowSymlinks counts the targets of symbolic links. Each file and
+	// directory is counted once, so link cycles terminate.
+	FollowSymlinks bool
+
+	// Submodules selects how git submodules listed in .gitmodules are counted.
+	Submodules SubmoduleMode
+
+	// ExcludeDirs are slash-separated directories, relative to the root of
+	// the tree, that are not counted, such as ghloc's own output directory.
+	ExcludeDirs []string
+
+	// MaxFileSize skips files larger than this many bytes without reading
+	// them. Zero means DefaultMaxFileSize; a negative value disables the cap.
+	MaxFileSize int64
+
+	// MaxAverageLineLength skips files whose average line is longer than this
+	// many bytes, which is typical of minified code. Zero means
+	// DefaultMaxAverageLineLength; a negative value disables the check.
+	MaxAverageLineLength int
+
+	// Cache, if set, reuses the results of files unchanged since an earlier
+	// count. The caller saves it afterwards.
+	Cache *Cache
+
+	// Logger receives a trace of the count: each file's language and how it
+	// was chosen or why it was skipped at debug level, unreadable files as
+	// warnings and a summary at info level. Nil discards the trace.
+	Logger *slog.Logger
+}
+
+// DefaultMaxFileSize is the default for Options.MaxFileSize. Source files
+// beyond it are almost always generated or vendored data.
+const DefaultMaxFileSize = 10 << 20
+
+// DefaultMaxAverageLineLength is the default for Options.MaxAverageLineLength,
+// matching scc's minified file detection.
+const DefaultMaxAverageLineLength = 255
+
+// DefaultTestPatterns covers the common test file conventions of popular languages.
+var DefaultTestPatterns = []string{
+	// Go
+	"*_test.go",
+	// JavaScript and TypeScript
+	"*.spec.js", "*.spec.jsx", "*.spec.ts", "*.spec.tsx",
+	"*.test.js", "*.test.jsx", "*.test.ts", "*.test.tsx",
+	"**/__tests__/**",
+	// Python
+	"test_*.py", "*_test.py",
+	// Ruby
+	"*_spec.rb", "*_test.rb",
+	// Java, Kotlin and Scala (Maven/Gradle layout)
+	"**/src/test/**",
+	// Rust, PHP, Python and others
+	"**/tests/**",
+}
+
+func (o Options) testPatterns() []string {
+	if o.TestPatterns == nil {
+		return DefaultTestPatterns
+	}
+	return o.TestPatterns
+}
+
+func (o Options) maxFileSize() int64 {
+	if o.MaxFileSize == 0 {
+		return DefaultMaxFileSize
+	}
+	return o.MaxFileSize
+}
+
+func (o Options) maxAverageLineLength() int {
+	if o.MaxAverageLineLength == 0 {
+		return DefaultMaxAverageLineLength
+	}
+	return o.MaxAverageLineLength
+}
+
+func (o Options) logger() *slog.Logger {
+	if o.Logger == nil {
+		return slog.New(slog.DiscardHandler)
+	}
+	return o.Logger
+}
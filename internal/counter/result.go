@@ -2,20 +2,105 @@ package counter
 
 // LOCResult holds the aggregate results of counting lines of code in a repository.
 type LOCResult struct {
-	TotalLines    int64
-	TotalCode     int64
-	TotalComments int64
-	TotalBlanks   int64
-	TotalFiles    int64
-	Languages     []LanguageStats
+	TotalLines      int64
+	TotalCode       int64
+	TotalComments   int64
+	TotalBlanks     int64
+	TotalFiles      int64
+	TotalComplexity int64
+	TotalBytes      int64
+	TotalULOC       int64 // unique lines across the whole repository
+	TotalTestCode   int64 // code lines in files classified as tests
+	Languages       []LanguageStats
+	Files           []FileStats
+	Submodules      []SubmoduleResult
+	Skipped         []SkippedFile // files that were not counted, in walk order
+	Incomplete      bool          // the count was cancelled before the whole tree was walked
+}
+
+// SkipReason explains why a file was not counted.
+type SkipReason string
+
+const (
+	SkipBinary          SkipReason = "binary"
+	SkipTooLarge        SkipReason = "too large"        // over Options.MaxFileSize
+	SkipMinified        SkipReason = "minified"         // over Options.MaxAverageLineLength
+	SkipUnreadable      SkipReason = "unreadable"       // see SkippedFile.Err
+	SkipUnknownLanguage SkipReason = "unknown language" // no language for its name, shebang or modeline
+)
+
+// SkippedFile records a file that was not counted.
+type SkippedFile struct {
+	Path   string // slash-separated, relative to the counted directory
+	Reason SkipReason
+	Err    error // the read error, for SkipUnreadable
+}
+
+// SkippedByReason counts the skipped files per reason.
+func (r *LOCResult) SkippedByReason() map[SkipReason]int {
+	counts := make(map[SkipReason]int)
+	for _, s := range r.Skipped {
+		counts[s.Reason]++
+	}
+	return counts
+}
+
+// SubmoduleResult holds the counts attributed to a single git submodule.
+type SubmoduleResult struct {
+	Name string
+	Path string // slash-separated, relative to the counted directory
+	LOCResult
+}
+
+// Dryness returns the ratio of unique lines to total lines (scc's DRYness).
+// Values near 1 mean little repetition; lower values mean more duplicated lines.
+func (r *LOCResult) Dryness() float64 {
+	if r.TotalLines == 0 {
+		return 0
+	}
+	return float64(r.TotalULOC) / float64(r.TotalLines)
 }
 
 // LanguageStats holds LOC statistics for a single language.
 type LanguageStats struct {
-	Language string
-	Lines    int64
-	Code     int64
-	Comments int64
-	Blanks   int64
-	Files    int64
+	Language   string
+	Lines      int64
+	Code       int64
+	Comments   int64
+	Blanks     int64
+	Files      int64
+	Complexity int64 // sum of scc's cyclomatic complexity estimate across files
+	Bytes      int64
+	ULOC       int64 // unique lines within this language
+	TestCode   int64 // code lines in test files; Code - TestCode is production code
+	TestFiles  int64
+	Category   string // see LanguageCategory; for groups, the category of the first file counted
+}
+
+// add accumulates a file's statistics.
+func (s *LanguageStats) add(f FileStats) {
+	s.Lines += f.Lines
+	s.Code += f.Code
+	s.Comments += f.Comments
+	s.Blanks += f.Blanks
+	s.Files++
+	s.Complexity += f.Complexity
+	s.Bytes += f.Bytes
+	if f.Test {
+		s.TestCode += f.Code
+		s.TestFiles++
+	}
+}
+
+// FileStats holds LOC statistics for a single counted file.
+type FileStats struct {
+	Path       string // slash-separated, relative to the counted directory
+	Language   string
+	Lines      int64
+	Code       int64
+	Comments   int64
+	Blanks     int64
+	Bytes      int64
+	Complexity int64
+	Test       bool // matched one of the test patterns
 }
@@ -0,0 +1,150 @@
+package counter
+
+import (
+	"bufio"
+	"bytes"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/boyter/scc/v3/processor"
+)
+
+// interpreterLanguages adds interpreters scc's shebang table does not know.
+var interpreterLanguages = map[string]string{
+	"dash":       "Shell",
+	"ash":        "Shell",
+	"nodejs":     "JavaScript",
+	"deno":       "TypeScript",
+	"bun":        "TypeScript",
+	"ts-node":    "TypeScript",
+	"pwsh":       "Powershell",
+	"osascript":  "AppleScript",
+	"tclsh":      "TCL",
+	"wish":       "TCL",
+	"runghc":     "Haskell",
+	"runhaskell": "Haskell",
+	"elixir":     "Elixir",
+	"guile":      "Scheme",
+	"julia":      "Julia",
+	"swift":      "Swift",
+	"make":       "Makefile",
+}
+
+// modeLanguages maps Vim filetypes and Emacs major modes that differ from
+// scc's language names.
+var modeLanguages = map[string]string{
+	"sh":           "Shell",
+	"shell-script": "Shell",
+	"bash":         "BASH",
+	"py":           "Python",
+	"js":           "JavaScript",
+	"js2":          "JavaScript",
+	"rb":           "Ruby",
+	"cperl":        "Perl",
+	"elisp":        "Emacs Lisp",
+	"make":         "Makefile",
+	"cpp":          "C++",
+	"ps1":          "Powershell",
+}
+
+var (
+	vimModeline       = regexp.MustCompile(`(?:^|\s)(?:vi|vim|ex)(?:[<=>]?\d+)?:.*?\b(?:ft|filetype|syntax)=([\w+.-]+)`)
+	emacsModeline     = regexp.MustCompile(`-\*-.*?\bmode:\s*([\w+.-]+)`)
+	emacsModeShort    = regexp.MustCompile(`-\*-\s*([\w+.-]+)\s*-\*-`)
+	interpreterSuffix = regexp.MustCompile(`[\d.]+$`)
+)
+
+// scriptLanguage identifies the language of an extensionless file from its
+// shebang line or a Vim/Emacs modeline, and reports which one decided. It
+// returns "" when neither names a language scc knows.
+func scriptLanguage(content []byte) (language, source string) {
+	if lang := shebangLanguage(content); lang != "" {
+		return lang, SourceShebang
+	}
+	if lang := modelineLanguage(content); lang != "" {
+		return lang, SourceModeline
+	}
+	return "", ""
+}
+
+// shebangLanguage maps the interpreter named on a "#!" first line, including
+// "/usr/bin/env [-S] [VAR=value] cmd" forms and versioned names like python3.12.
+func shebangLanguage(content []byte) string {
+	if !bytes.HasPrefix(content, []byte("#!")) {
+		return ""
+	}
+	line, _, _ := bytes.Cut(content[2:], []byte("\n"))
+	fields := strings.Fields(string(line))
+	if len(fields) == 0 {
+		return ""
+	}
+
+	interpreter := path.Base(fields[0])
+	if interpreter == "env" {
+		interpreter = ""
+		for _, f := range fields[1:] {
+			if strings.HasPrefix(f, "-") || strings.Contains(f, "=") {
+				continue // env flags such as -S and variable assignments
+			}
+			interpreter = path.Base(f)
+			break
+		}
+	}
+	if interpreter == "" {
+		return ""
+	}
+
+	if lang := interpreterLanguage(interpreter); lang != "" {
+		return lang
+	}
+	return interpreterLanguage(interpreterSuffix.ReplaceAllString(interpreter, ""))
+}
+
+// interpreterLanguage looks up a single interpreter name.
+func interpreterLanguage(interpreter string) string {
+	for lang, cmds := range processor.ShebangLookup {
+		for _, cmd := range cmds {
+			if cmd == interpreter {
+				return lang
+			}
+		}
+	}
+	return interpreterLanguages[interpreter]
+}
+
+// modelineLanguage looks for a Vim or Emacs modeline in the first or last five
+// lines of content, where both editors look for them.
+func modelineLanguage(content []byte) string {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	candidates := lines
+	if len(lines) > 10 {
+		candidates = append(lines[:5:5], lines[len(lines)-5:]...)
+	}
+
+	for _, line := range candidates {
+		var mode string
+		if m := vimModeline.FindStringSubmatch(line); m != nil {
+			mode = m[1]
+		} else if m := emacsModeline.FindStringSubmatch(line); m != nil {
+			mode = m[1]
+		} else if m := emacsModeShort.FindStringSubmatch(line); m != nil {
+			mode = m[1]
+		} else {
+			continue
+		}
+
+		mode = strings.ToLower(strings.TrimSuffix(mode, "-mode"))
+		if lang, ok := modeLanguages[mode]; ok {
+			return lang
+		}
+		if lang, ok := canonicalLanguage(mode); ok {
+			return lang
+		}
+	}
+	return ""
+}
@@ -0,0 +1,81 @@
+package counter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/boyter/scc/v3/processor"
+)
+
+func TestScriptLanguage(t *testing.T) {
+	initProcessor()
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"bash", "#!/bin/bash\necho hi\n", "BASH"},
+		{"sh", "#!/bin/sh\necho hi\n", "Shell"},
+		{"env python3", "#!/usr/bin/env python3\nprint('hi')\n", "Python"},
+		{"versioned python", "#!/usr/local/bin/python3.12\nprint('hi')\n", "Python"},
+		{"env -S with flags", "#!/usr/bin/env -S python3 -u\nprint('hi')\n", "Python"},
+		{"env with assignment", "#!/usr/bin/env NODE_ENV=production node\nconsole.log(1)\n", "JavaScript"},
+		{"node", "#!/usr/bin/env node\nconsole.log(1)\n", "JavaScript"},
+		{"ruby with space", "#! /usr/bin/ruby -w\nputs 1\n", "Ruby"},
+		{"perl", "#!/usr/bin/perl\nprint 1;\n", "Perl"},
+		{"dash extra", "#!/bin/dash\necho hi\n", "Shell"},
+		{"deno extra", "#!/usr/bin/env -S deno run\nconsole.log(1)\n", "TypeScript"},
+		{"unknown interpreter", "#!/usr/bin/frobnicate\nstuff\n", ""},
+		{"bare env", "#!/usr/bin/env\n", ""},
+		{"no shebang", "just some text\n", ""},
+		{"vim ft", "echo hi\n# vim: set ft=sh :\n", "Shell"},
+		{"vim filetype", "print(1)\n# vim: filetype=python\n", "Python"},
+		{"vi syntax", "# vi: syntax=ruby\nputs 1\n", "Ruby"},
+		{"emacs mode", "# -*- mode: python; coding: utf-8 -*-\nprint(1)\n", "Python"},
+		{"emacs short", "; -*- emacs-lisp -*-\n(message \"hi\")\n", "Emacs Lisp"},
+		{"emacs sh-mode", "# -*- mode: sh -*-\necho hi\n", "Shell"},
+		{"shebang beats modeline", "#!/bin/bash\n# vim: ft=python\n", "BASH"},
+		{"modeline at end", "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\n# vim: ft=perl\n", "Perl"},
+		{"modeline in middle ignored", "a\nb\nc\nd\ne\n# vim: ft=perl\nf\ng\nh\ni\nj\nk\n", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := scriptLanguage([]byte(tt.content)); got != tt.want {
+				t.Errorf("scriptLanguage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCount_ExtensionlessScripts(t *testing.T) {
+	dir := t.TempDir()
+
+	os.MkdirAll(filepath.Join(dir, "bin"), 0755)
+	os.WriteFile(filepath.Join(dir, "bin", "deploy"), []byte("#!/usr/bin/env bash\nset -e\necho deploying\n"), 0755)
+	os.WriteFile(filepath.Join(dir, "bin", "report"), []byte("#!/usr/bin/env python3\nprint('report')\n"), 0755)
+	os.WriteFile(filepath.Join(dir, "NOTES"), []byte("plain notes without a shebang\n"), 0644)
+
+	result, err := Count(dir, Options{})
+	if err != nil {
+		t.Fatalf("Count() error: %v", err)
+	}
+
+	got := fileLanguages(result)
+	if got["bin/deploy"] != "BASH" {
+		t.Errorf("bin/deploy: got %q, want BASH", got["bin/deploy"])
+	}
+	if got["bin/report"] != "Python" {
+		t.Errorf("bin/report: got %q, want Python", got["bin/report"])
+	}
+	if _, ok := got["NOTES"]; ok {
+		t.Error("extensionless file without shebang should be skipped")
+	}
+	for _, lang := range result.Languages {
+		if lang.Language == processor.SheBang {
+			t.Errorf("no files should be attributed to %q", processor.SheBang)
+		}
+	}
+}
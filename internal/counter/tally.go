@@ -0,0 +1,71 @@
+package counter
+
+import (
+	"cmp"
+	"slices"
+)
+
+// tally accumulates file statistics into per-language and overall totals.
+type tally struct {
+	languages     map[string]*LanguageStats
+	languageLines map[string]lineSet
+	totals        LanguageStats // files that count toward the result totals
+	uniqueLines   lineSet
+	files         []FileStats
+}
+
+func newTally() *tally {
+	return &tally{
+		languages:     make(map[string]*LanguageStats),
+		languageLines: make(map[string]lineSet),
+		uniqueLines:   make(lineSet),
+	}
+}
+
+// add records a counted file. Files outside the totals (see
+// LanguageRules.CodeOnly) still contribute to their language's stats.
+func (t *tally) add(file FileStats, category string, inTotals bool, content []byte) {
+	t.files = append(t.files, file)
+
+	stats, ok := t.languages[file.Language]
+	if !ok {
+		stats = &LanguageStats{Language: file.Language, Category: category}
+		t.languages[file.Language] = stats
+		t.languageLines[file.Language] = make(lineSet)
+	}
+	stats.add(file)
+	t.languageLines[file.Language].add(content)
+
+	if inTotals {
+		t.totals.add(file)
+		t.uniqueLines.add(content)
+	}
+}
+
+// result converts the tally into a LOCResult.
+func (t *tally) result() *LOCResult {
+	result := &LOCResult{
+		TotalLines:      t.totals.Lines,
+		TotalCode:       t.totals.Code,
+		TotalComments:   t.totals.Comments,
+		TotalBlanks:     t.totals.Blanks,
+		TotalFiles:      t.totals.Files,
+		TotalComplexity: t.totals.Complexity,
+		TotalBytes:      t.totals.Bytes,
+		TotalULOC:       int64(len(t.uniqueLines)),
+		TotalTestCode:   t.totals.TestCode,
+		Files:           t.files,
+	}
+	for _, stats := range t.languages {
+		stats.ULOC = int64(len(t.languageLines[stats.Language]))
+		result.Languages = append(result.Languages, *stats)
+	}
+	// Largest first, with ties broken by name so output is reproducible
+	slices.SortFunc(result.Languages, func(a, b LanguageStats) int {
+		if c := cmp.Compare(b.Code, a.Code); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Language, b.Language)
+	})
+	return result
+}
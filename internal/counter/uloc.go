@@ -0,0 +1,25 @@
+package counter
+
+import (
+	"bytes"
+	"hash/fnv"
+)
+
+// lineSet tracks unique lines by their 64-bit FNV-1a hash. Like scc's ULOC mode,
+// every distinct line counts once, including blank and comment lines, but only
+// hashes are retained so memory stays bounded on large repositories.
+type lineSet map[uint64]struct{}
+
+// add records each line of content in the set.
+func (s lineSet) add(content []byte) {
+	content = bytes.TrimRight(content, "\n")
+	if len(content) == 0 {
+		return
+	}
+	h := fnv.New64a()
+	for line := range bytes.SplitSeq(content, []byte("\n")) {
+		h.Reset()
+		h.Write(line)
+		s[h.Sum64()] = struct{}{}
+	}
+}
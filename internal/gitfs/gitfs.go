@@ -0,0 +1,179 @@
+// Package gitfs exposes the tree of a git commit as a read-only fs.FS, reading
+// blobs straight from the object database so no checkout is needed.
+package gitfs
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rjwalters/ghloc/internal/memfs"
+)
+
+// FS is the tree of a single commit. Submodules (gitlinks) appear as empty
+// directories, as they do in a checkout without submodules initialized.
+// Symlinks are followed within the tree. FS is safe for concurrent use; Close
+// stops the background git process.
+type FS struct {
+	*memfs.FS
+	repo   string
+	hashes map[string]string // blob object IDs by path
+
+	mu    sync.Mutex
+	batch *catFile
+}
+
+// Open lists the tree of rev in the repository at repo, which may be bare.
+func Open(repo, rev string) (*FS, error) {
+	out, err := git(repo, "ls-tree", "-r", "-t", "-l", "-z", "--full-tree", rev+"^{tree}")
+	if err != nil {
+		return nil, err
+	}
+
+	fsys := &FS{FS: memfs.New(), repo: repo, hashes: make(map[string]string)}
+	for _, rec := range bytes.Split(out, []byte{0}) {
+		if len(rec) == 0 {
+			continue
+		}
+		if err := fsys.addEntry(string(rec)); err != nil {
+			return nil, err
+		}
+	}
+	return fsys, nil
+}
+
+// addEntry adds one "<mode> <type> <object> <size>\t<path>" record of
+// git ls-tree -l -z output.
+func (fsys *FS) addEntry(rec string) error {
+	meta, name, ok := strings.Cut(rec, "\t")
+	fields := strings.Fields(meta)
+	if !ok || len(fields) != 4 {
+		return fmt.Errorf("ls-tree: malformed entry %q", rec)
+	}
+
+	var mode fs.FileMode
+	switch fields[0] {
+	case "040000", "160000": // tree or submodule
+		return fsys.AddDir(name)
+	case "120000":
+		mode = fs.ModeSymlink | 0777
+	case "100755":
+		mode = 0755
+	default:
+		mode = 0644
+	}
+	size, err := strconv.ParseInt(fields[3], 10, 64)
+	if err != nil {
+		return fmt.Errorf("ls-tree: bad size in %q", rec)
+	}
+	hash := fields[2]
+	fsys.hashes[name] = hash
+	return fsys.Add(name, mode, size, time.Time{}, func() ([]byte, error) { return fsys.blob(hash) })
+}
+
+// Hash returns the object ID of the blob at name, following symlinks.
+func (fsys *FS) Hash(name string) (string, bool) {
+	p, err := fsys.Resolve(name)
+	if err != nil {
+		return "", false
+	}
+	hash, ok := fsys.hashes[p]
+	return hash, ok
+}
+
+// Close stops the git process used to read blobs.
+func (fsys *FS) Close() error {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	if fsys.batch == nil {
+		return nil
+	}
+	err := fsys.batch.close()
+	fsys.batch = nil
+	return err
+}
+
+// blob reads the contents of a blob, starting git cat-file on first use.
+func (fsys *FS) blob(hash string) ([]byte, error) {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	if fsys.batch == nil {
+		b, err := startCatFile(fsys.repo)
+		if err != nil {
+			return nil, err
+		}
+		fsys.batch = b
+	}
+	return fsys.batch.read(hash)
+}
+
+// catFile is a running `git cat-file --batch` process.
+type catFile struct {
+	cmd *exec.Cmd
+	in  io.WriteCloser
+	out *bufio.Reader
+}
+
+func startCatFile(repo string) (*catFile, error) {
+	cmd := exec.Command("git", "-C", repo, "cat-file", "--batch")
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start git cat-file: %w", err)
+	}
+	return &catFile{cmd: cmd, in: in, out: bufio.NewReader(out)}, nil
+}
+
+// read fetches one object; the reply is "<oid> <type> <size>\n<content>\n".
+func (c *catFile) read(hash string) ([]byte, error) {
+	if _, err := io.WriteString(c.in, hash+"\n"); err != nil {
+		return nil, fmt.Errorf("git cat-file: %w", err)
+	}
+	header, err := c.out.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("git cat-file: %w", err)
+	}
+	fields := strings.Fields(header)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("git cat-file: %s", strings.TrimSpace(header))
+	}
+	size, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return nil, fmt.Errorf("git cat-file: bad header %q", header)
+	}
+	data := make([]byte, size+1)
+	if _, err := io.ReadFull(c.out, data); err != nil {
+		return nil, fmt.Errorf("git cat-file: %w", err)
+	}
+	return data[:size], nil
+}
+
+func (c *catFile) close() error {
+	c.in.Close()
+	return c.cmd.Wait()
+}
+
+// git runs a git command in repo and returns its stdout.
+func git(repo string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", append([]string{"-C", repo}, args...)...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
@@ -0,0 +1,125 @@
+package gitfs
+
+import (
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+// initRepo creates a repository with a single commit and returns its path.
+func initRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "src", "util"), 0755)
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("# demo\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "src", "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "src", "util", "util.go"), []byte("package util\n"), 0644)
+	os.Symlink("src/main.go", filepath.Join(dir, "main.go"))
+	os.Symlink("../../README.md", filepath.Join(dir, "src", "util", "readme"))
+
+	runGit(t, dir, "init", "-q")
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "initial")
+	return dir
+}
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	dir := initRepo(t)
+
+	// Later worktree changes must not show through.
+	os.WriteFile(filepath.Join(dir, "src", "main.go"), []byte("changed\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "untracked.go"), []byte("package x\n"), 0644)
+
+	fsys, err := Open(dir, "HEAD")
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	defer fsys.Close()
+
+	data, err := fs.ReadFile(fsys, "src/main.go")
+	if err != nil || string(data) != "package main\n\nfunc main() {}\n" {
+		t.Errorf("ReadFile(src/main.go) = %q, %v", data, err)
+	}
+	if _, err := fs.Stat(fsys, "untracked.go"); err == nil {
+		t.Error("untracked file should not be in the tree")
+	}
+
+	data, err = fs.ReadFile(fsys, "src/util/readme")
+	if err != nil || string(data) != "# demo\n" {
+		t.Errorf("ReadFile through relative symlink = %q, %v", data, err)
+	}
+	if target, err := fs.ReadLink(fsys, "main.go"); err != nil || target != "src/main.go" {
+		t.Errorf("ReadLink(main.go) = %q, %v", target, err)
+	}
+	if hash, ok := fsys.Hash("README.md"); !ok || len(hash) != 40 {
+		t.Errorf("Hash(README.md) = %q, %v", hash, ok)
+	}
+
+	if err := fstest.TestFS(fsys, "README.md", "src/main.go", "src/util/util.go", "main.go"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestOpen_EscapingSymlinks(t *testing.T) {
+	dir := initRepo(t)
+	os.Symlink("/etc/passwd", filepath.Join(dir, "absolute"))
+	os.Symlink("../outside", filepath.Join(dir, "parent"))
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "links")
+
+	fsys, err := Open(dir, "HEAD")
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	defer fsys.Close()
+
+	for _, name := range []string{"absolute", "parent"} {
+		if _, err := fs.Stat(fsys, name); err == nil {
+			t.Errorf("Stat(%s): symlink leaving the tree should be dangling", name)
+		}
+		if _, err := fsys.Lstat(name); err != nil {
+			t.Errorf("Lstat(%s) error: %v", name, err)
+		}
+	}
+}
+
+func TestOpen_BareRepository(t *testing.T) {
+	dir := initRepo(t)
+	bare := filepath.Join(t.TempDir(), "repo.git")
+	runGit(t, dir, "clone", "-q", "--bare", dir, bare)
+
+	fsys, err := Open(bare, "HEAD")
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	defer fsys.Close()
+
+	entries, err := fs.ReadDir(fsys, "src")
+	if err != nil {
+		t.Fatalf("ReadDir(src) error: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Name() != "main.go" || !entries[1].IsDir() {
+		t.Errorf("ReadDir(src) = %v", entries)
+	}
+}
+
+func TestOpen_UnknownRevision(t *testing.T) {
+	dir := initRepo(t)
+	if _, err := Open(dir, "no-such-branch"); err == nil {
+		t.Fatal("expected error for unknown revision")
+	}
+}
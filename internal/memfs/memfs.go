@@ -0,0 +1,320 @@
+// Package memfs provides a read-only fs.FS over a directory tree held in
+// memory, whose file contents may be loaded lazily. It backs the git tree and
+// archive file systems.
+package memfs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// maxLinkHops bounds symlink resolution, matching the usual Linux limit.
+const maxLinkHops = 40
+
+// LoadFunc returns the contents of a file, or the target of a symlink, in a
+// slice the caller may modify.
+type LoadFunc func() ([]byte, error)
+
+// FS is an in-memory directory tree. Symlinks are followed as long as their
+// targets stay inside the tree; others behave as dangling links. An FS must
+// not be modified once in use.
+type FS struct {
+	entries map[string]*entry // keyed by slash-separated path; "." is the root
+}
+
+// entry is a single file, directory or symlink.
+type entry struct {
+	path     string
+	name     string
+	mode     fs.FileMode
+	size     int64
+	modTime  time.Time
+	load     LoadFunc
+	children []string // names of the entries in a directory, sorted
+}
+
+// New returns an empty tree.
+func New() *FS {
+	return &FS{entries: map[string]*entry{".": {path: ".", name: ".", mode: fs.ModeDir | 0555}}}
+}
+
+// Bytes returns a LoadFunc serving copies of data.
+func Bytes(data []byte) LoadFunc {
+	return func() ([]byte, error) { return bytes.Clone(data), nil }
+}
+
+// AddDir adds a directory and any missing parents. Adding an existing
+// directory is a no-op.
+func (fsys *FS) AddDir(name string) error {
+	_, err := fsys.dir(name)
+	return err
+}
+
+// Add adds a regular file or symlink, according to mode, creating missing
+// parent directories. load is called each time the file is read.
+func (fsys *FS) Add(name string, mode fs.FileMode, size int64, modTime time.Time, load LoadFunc) error {
+	if !fs.ValidPath(name) || name == "." {
+		return fmt.Errorf("memfs: invalid path %q", name)
+	}
+	if mode.IsDir() {
+		return fsys.AddDir(name)
+	}
+	if _, exists := fsys.entries[name]; exists {
+		return fmt.Errorf("memfs: %s added twice", name)
+	}
+	parent, err := fsys.dir(path.Dir(name))
+	if err != nil {
+		return err
+	}
+	e := &entry{path: name, name: path.Base(name), mode: mode, size: size, modTime: modTime, load: load}
+	fsys.entries[name] = e
+	parent.children = insertSorted(parent.children, e.name)
+	return nil
+}
+
+// dir returns the directory at name, creating it and its parents if needed.
+func (fsys *FS) dir(name string) (*entry, error) {
+	if e, ok := fsys.entries[name]; ok {
+		if !e.mode.IsDir() {
+			return nil, fmt.Errorf("memfs: %s is not a directory", name)
+		}
+		return e, nil
+	}
+	if !fs.ValidPath(name) {
+		return nil, fmt.Errorf("memfs: invalid path %q", name)
+	}
+	parent, err := fsys.dir(path.Dir(name))
+	if err != nil {
+		return nil, err
+	}
+	e := &entry{path: name, name: path.Base(name), mode: fs.ModeDir | 0555}
+	fsys.entries[name] = e
+	parent.children = insertSorted(parent.children, e.name)
+	return e, nil
+}
+
+func insertSorted(names []string, name string) []string {
+	i, _ := slices.BinarySearch(names, name)
+	return slices.Insert(names, i, name)
+}
+
+// Resolve returns the symlink-free path of name.
+func (fsys *FS) Resolve(name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: "resolve", Path: name, Err: fs.ErrInvalid}
+	}
+	p, err := fsys.resolvePath(name, 0)
+	if err != nil {
+		return "", &fs.PathError{Op: "resolve", Path: name, Err: err}
+	}
+	return p, nil
+}
+
+// Open implements fs.FS.
+func (fsys *FS) Open(name string) (fs.File, error) {
+	e, err := fsys.resolve("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if e.mode.IsDir() {
+		return &dir{info: fileInfo{e}, entries: fsys.dirEntries(e)}, nil
+	}
+	data, err := e.load()
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &file{info: fileInfo{e}, Reader: bytes.NewReader(data)}, nil
+}
+
+// ReadFile implements fs.ReadFileFS.
+func (fsys *FS) ReadFile(name string) ([]byte, error) {
+	e, err := fsys.resolve("read", name)
+	if err != nil {
+		return nil, err
+	}
+	if e.mode.IsDir() {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: errors.New("is a directory")}
+	}
+	data, err := e.load()
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	return data, nil
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (fsys *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	e, err := fsys.resolve("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !e.mode.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+	return fsys.dirEntries(e), nil
+}
+
+// Stat implements fs.StatFS.
+func (fsys *FS) Stat(name string) (fs.FileInfo, error) {
+	e, err := fsys.resolve("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return fileInfo{e}, nil
+}
+
+// Lstat implements fs.ReadLinkFS.
+func (fsys *FS) Lstat(name string) (fs.FileInfo, error) {
+	e, err := fsys.lookup("lstat", name)
+	if err != nil {
+		return nil, err
+	}
+	return fileInfo{e}, nil
+}
+
+// ReadLink implements fs.ReadLinkFS.
+func (fsys *FS) ReadLink(name string) (string, error) {
+	e, err := fsys.lookup("readlink", name)
+	if err != nil {
+		return "", err
+	}
+	if e.mode&fs.ModeSymlink == 0 {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	target, err := e.load()
+	if err != nil {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: err}
+	}
+	return string(target), nil
+}
+
+// lookup returns the entry at name, resolving symlinks in its parent
+// directories but not in its final element.
+func (fsys *FS) lookup(op, name string) (*entry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return fsys.entries["."], nil
+	}
+	parent, err := fsys.resolvePath(path.Dir(name), 0)
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	e, ok := fsys.entries[path.Join(parent, path.Base(name))]
+	if !ok {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return e, nil
+}
+
+// resolve returns the entry at name, following symlinks.
+func (fsys *FS) resolve(op, name string) (*entry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	p, err := fsys.resolvePath(name, 0)
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	return fsys.entries[p], nil
+}
+
+// resolvePath returns the symlink-free path of name. Links with absolute
+// targets or targets outside the tree are treated as dangling.
+func (fsys *FS) resolvePath(name string, hops int) (string, error) {
+	resolved := "."
+	rest := strings.Split(name, "/")
+	for len(rest) > 0 {
+		next := path.Join(resolved, rest[0])
+		rest = rest[1:]
+		e, ok := fsys.entries[next]
+		if !ok {
+			return "", fs.ErrNotExist
+		}
+		if e.mode&fs.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+
+		if hops++; hops > maxLinkHops {
+			return "", errors.New("too many levels of symbolic links")
+		}
+		target, err := e.load()
+		if err != nil {
+			return "", err
+		}
+		t := path.Join(resolved, string(target))
+		if path.IsAbs(string(target)) || !fs.ValidPath(t) {
+			return "", fs.ErrNotExist
+		}
+		if resolved, err = fsys.resolvePath(t, hops); err != nil {
+			return "", err
+		}
+	}
+	return resolved, nil
+}
+
+// dirEntries lists the entries of directory e.
+func (fsys *FS) dirEntries(e *entry) []fs.DirEntry {
+	list := make([]fs.DirEntry, 0, len(e.children))
+	for _, child := range e.children {
+		list = append(list, fs.FileInfoToDirEntry(fileInfo{fsys.entries[path.Join(e.path, child)]}))
+	}
+	return list
+}
+
+// fileInfo adapts an entry to fs.FileInfo.
+type fileInfo struct{ e *entry }
+
+func (fi fileInfo) Name() string       { return fi.e.name }
+func (fi fileInfo) Size() int64        { return fi.e.size }
+func (fi fileInfo) Mode() fs.FileMode  { return fi.e.mode }
+func (fi fileInfo) ModTime() time.Time { return fi.e.modTime }
+func (fi fileInfo) IsDir() bool        { return fi.e.mode.IsDir() }
+func (fi fileInfo) Sys() any           { return nil }
+
+// file is an open file.
+type file struct {
+	info fileInfo
+	*bytes.Reader
+}
+
+func (f *file) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *file) Close() error               { return nil }
+
+// dir is an open directory.
+type dir struct {
+	info    fileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *dir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *dir) Close() error               { return nil }
+
+func (d *dir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: errors.New("is a directory")}
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (d *dir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	rest = rest[:min(n, len(rest))]
+	d.offset += len(rest)
+	return rest, nil
+}
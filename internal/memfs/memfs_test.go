@@ -0,0 +1,99 @@
+package memfs
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func newTree(t *testing.T) *FS {
+	t.Helper()
+	tree := New()
+	files := []struct {
+		name string
+		mode fs.FileMode
+		data string
+	}{
+		{"README.md", 0644, "# demo\n"},
+		{"src/main.go", 0644, "package main\n"},
+		{"src/util/util.go", 0644, "package util\n"},
+		{"main.go", fs.ModeSymlink, "src/main.go"},
+		{"src/util/up", fs.ModeSymlink, "../.."},
+		{"escape", fs.ModeSymlink, "../outside"},
+		{"absolute", fs.ModeSymlink, "/etc/passwd"},
+	}
+	for _, f := range files {
+		if err := tree.Add(f.name, f.mode, int64(len(f.data)), time.Time{}, Bytes([]byte(f.data))); err != nil {
+			t.Fatalf("Add(%s) error: %v", f.name, err)
+		}
+	}
+	if err := tree.AddDir("empty"); err != nil {
+		t.Fatalf("AddDir(empty) error: %v", err)
+	}
+	return tree
+}
+
+func TestFS(t *testing.T) {
+	tree := newTree(t)
+
+	if data, err := fs.ReadFile(tree, "src/util/up/main.go"); err != nil || string(data) != "package main\n" {
+		t.Errorf("ReadFile through directory symlink = %q, %v", data, err)
+	}
+	if p, err := tree.Resolve("src/util/up/main.go"); err != nil || p != "src/main.go" {
+		t.Errorf("Resolve() = %q, %v; want src/main.go", p, err)
+	}
+	for _, name := range []string{"escape", "absolute"} {
+		if _, err := fs.Stat(tree, name); err == nil {
+			t.Errorf("Stat(%s): symlink leaving the tree should be dangling", name)
+		}
+		if info, err := tree.Lstat(name); err != nil || info.Mode()&fs.ModeSymlink == 0 {
+			t.Errorf("Lstat(%s) = %v, %v", name, info, err)
+		}
+	}
+
+	entries, err := fs.ReadDir(tree, ".")
+	if err != nil {
+		t.Fatalf("ReadDir error: %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	want := []string{"README.md", "absolute", "empty", "escape", "main.go", "src"}
+	if len(names) != len(want) {
+		t.Fatalf("ReadDir(.) = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("ReadDir(.)[%d] = %s, want %s", i, names[i], want[i])
+		}
+	}
+}
+
+func TestFS_Conformance(t *testing.T) {
+	tree := New()
+	tree.Add("a/b.txt", 0644, 2, time.Time{}, Bytes([]byte("b\n")))
+	tree.Add("c.txt", 0644, 2, time.Time{}, Bytes([]byte("c\n")))
+	tree.Add("link.txt", fs.ModeSymlink, 7, time.Time{}, Bytes([]byte("a/b.txt")))
+	tree.AddDir("empty")
+
+	if err := fstest.TestFS(tree, "a/b.txt", "c.txt", "link.txt", "empty"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFS_AddErrors(t *testing.T) {
+	tree := New()
+	tree.Add("file", 0644, 0, time.Time{}, Bytes(nil))
+
+	if err := tree.Add("file", 0644, 0, time.Time{}, Bytes(nil)); err == nil {
+		t.Error("expected error adding a file twice")
+	}
+	if err := tree.Add("file/child", 0644, 0, time.Time{}, Bytes(nil)); err == nil {
+		t.Error("expected error adding below a file")
+	}
+	if err := tree.Add("../x", 0644, 0, time.Time{}, Bytes(nil)); err == nil {
+		t.Error("expected error for a path outside the tree")
+	}
+}
@@ -0,0 +1,245 @@
+package metric
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rjwalters/ghloc/internal/badge"
+	"github.com/rjwalters/ghloc/internal/store"
+)
+
+// Metric describes a value derived from a snapshot that can be charted over
+// time or shown on a badge.
+type Metric struct {
+	Name  string // identifier used on the command line, e.g. "complexity"
+	Label string // badge label, e.g. "complexity"
+	Title string // chart title, e.g. "Cyclomatic Complexity"
+
+	// Value extracts the metric from a snapshot. It reports false when the
+	// snapshot predates the metric or the value is otherwise undefined.
+	Value func(store.Snapshot) (float64, bool)
+
+	// Format renders a value for display on a badge.
+	Format func(float64) string
+}
+
+var registry = map[string]Metric{
+	"loc": {
+		Name:  "loc",
+		Label: "lines of code",
+		Title: "Lines of Code",
+		Value: func(s store.Snapshot) (float64, bool) {
+			return float64(s.TotalLOC), true
+		},
+		Format: formatCount,
+	},
+	"complexity": {
+		Name:  "complexity",
+		Label: "complexity",
+		Title: "Cyclomatic Complexity",
+		Value: func(s store.Snapshot) (float64, bool) {
+			return float64(s.TotalComplexity), s.TotalComplexity > 0
+		},
+		Format: formatCount,
+	},
+	"complexity-per-kloc": {
+		Name:  "complexity-per-kloc",
+		Label: "complexity/kloc",
+		Title: "Complexity per 1k Lines of Code",
+		Value: func(s store.Snapshot) (float64, bool) {
+			if s.TotalComplexity == 0 || s.TotalLOC == 0 {
+				return 0, false
+			}
+			return float64(s.TotalComplexity) / (float64(s.TotalLOC) / 1000), true
+		},
+		Format: formatDecimal,
+	},
+	"bytes": {
+		Name:  "bytes",
+		Label: "code size",
+		Title: "Code Size (bytes)",
+		Value: func(s store.Snapshot) (float64, bool) {
+			return float64(s.TotalBytes), s.TotalBytes > 0
+		},
+		Format: formatBytes,
+	},
+	"uloc": {
+		Name:  "uloc",
+		Label: "unique lines",
+		Title: "Unique Lines of Code",
+		Value: func(s store.Snapshot) (float64, bool) {
+			return float64(s.TotalULOC), s.TotalULOC > 0
+		},
+		Format: formatCount,
+	},
+	"dryness": {
+		Name:  "dryness",
+		Label: "DRYness",
+		Title: "DRYness (% unique lines)",
+		Value: func(s store.Snapshot) (float64, bool) {
+			return s.Dryness * 100, s.Dryness > 0
+		},
+		Format: formatPercent,
+	},
+	"cost": {
+		Name:  "cost",
+		Label: "estimated cost",
+		Title: "Estimated Cost (COCOMO)",
+		Value: func(s store.Snapshot) (float64, bool) {
+			if s.Cocomo == nil {
+				return 0, false
+			}
+			return s.Cocomo.Cost, true
+		},
+		Format: formatDollars,
+	},
+	"effort": {
+		Name:  "effort",
+		Label: "estimated effort",
+		Title: "Estimated Effort (person-months, COCOMO)",
+		Value: func(s store.Snapshot) (float64, bool) {
+			if s.Cocomo == nil {
+				return 0, false
+			}
+			return s.Cocomo.EffortMonths, true
+		},
+		Format: formatPersonMonths,
+	},
+	"test-ratio": {
+		Name:  "test-ratio",
+		Label: "test:code",
+		Title: "Test to Production Code Ratio",
+		Value: func(s store.Snapshot) (float64, bool) {
+			if s.TotalTestCode == nil {
+				return 0, false // predates the test/production split
+			}
+			production := s.TotalLOC - *s.TotalTestCode
+			if production <= 0 {
+				return 0, false
+			}
+			return float64(*s.TotalTestCode) / float64(production), true
+		},
+		Format: formatRatio,
+	},
+	"comment-density": {
+		Name:  "comment-density",
+		Label: "comments",
+		Title: "Comment Density (% of code and comment lines)",
+		Value: func(s store.Snapshot) (float64, bool) {
+			var code, comments int64
+			for _, l := range s.Languages {
+				code += l.Code
+				comments += l.Comments
+			}
+			return CommentDensity(code, comments)
+		},
+		Format: formatPercent,
+	},
+	"blank-ratio": {
+		Name:  "blank-ratio",
+		Label: "blank lines",
+		Title: "Blank Lines (% of all lines)",
+		Value: func(s store.Snapshot) (float64, bool) {
+			var lines, blanks int64
+			for _, l := range s.Languages {
+				lines += l.Lines
+				blanks += l.Blanks
+			}
+			if lines == 0 {
+				return 0, false
+			}
+			return float64(blanks) / float64(lines) * 100, true
+		},
+		Format: formatPercent,
+	},
+}
+
+// CommentDensity returns comments as a percentage of code and comment lines,
+// reporting false when there are neither.
+func CommentDensity(code, comments int64) (float64, bool) {
+	if code+comments == 0 {
+		return 0, false
+	}
+	return float64(comments) / float64(code+comments) * 100, true
+}
+
+// Lookup returns the metric registered under name.
+func Lookup(name string) (Metric, error) {
+	m, ok := registry[name]
+	if !ok {
+		return Metric{}, fmt.Errorf("unknown metric %q (available: %s)", name, strings.Join(Names(), ", "))
+	}
+	return m, nil
+}
+
+// Names returns the names of all registered metrics in sorted order.
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// ParseList parses a comma-separated list of metric names, ignoring blanks.
+func ParseList(list string) ([]Metric, error) {
+	var metrics []Metric
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		m, err := Lookup(name)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+func formatCount(v float64) string {
+	return badge.FormatLOC(int64(v))
+}
+
+func formatDecimal(v float64) string {
+	return fmt.Sprintf("%.1f", v)
+}
+
+func formatPercent(v float64) string {
+	return fmt.Sprintf("%.0f%%", v)
+}
+
+func formatBytes(v float64) string {
+	switch {
+	case v >= 1_000_000_000:
+		return fmt.Sprintf("%.1f GB", v/1_000_000_000)
+	case v >= 1_000_000:
+		return fmt.Sprintf("%.1f MB", v/1_000_000)
+	case v >= 1_000:
+		return fmt.Sprintf("%.1f kB", v/1_000)
+	default:
+		return fmt.Sprintf("%.0f B", v)
+	}
+}
+
+func formatDollars(v float64) string {
+	switch {
+	case v >= 1_000_000:
+		return fmt.Sprintf("$%.1fM", v/1_000_000)
+	case v >= 1_000:
+		return fmt.Sprintf("$%.1fk", v/1_000)
+	default:
+		return fmt.Sprintf("$%.0f", v)
+	}
+}
+
+func formatPersonMonths(v float64) string {
+	return fmt.Sprintf("%.1f person-months", v)
+}
+
+func formatRatio(v float64) string {
+	return fmt.Sprintf("%.2f", v)
+}
@@ -0,0 +1,140 @@
+package metric
+
+import (
+	"testing"
+
+	"github.com/rjwalters/ghloc/internal/store"
+)
+
+func TestLookup(t *testing.T) {
+	for _, name := range Names() {
+		m, err := Lookup(name)
+		if err != nil {
+			t.Errorf("Lookup(%q) error: %v", name, err)
+		}
+		if m.Name != name {
+			t.Errorf("Lookup(%q).Name = %q", name, m.Name)
+		}
+	}
+
+	if _, err := Lookup("nope"); err == nil {
+		t.Error("expected error for unknown metric")
+	}
+}
+
+func TestParseList(t *testing.T) {
+	metrics, err := ParseList("complexity, ,loc")
+	if err != nil {
+		t.Fatalf("ParseList() error: %v", err)
+	}
+	if len(metrics) != 2 || metrics[0].Name != "complexity" || metrics[1].Name != "loc" {
+		t.Errorf("ParseList() = %+v", metrics)
+	}
+
+	if _, err := ParseList("loc,bogus"); err == nil {
+		t.Error("expected error for unknown metric in list")
+	}
+}
+
+func TestComplexityPerKLOC(t *testing.T) {
+	m, _ := Lookup("complexity-per-kloc")
+
+	v, ok := m.Value(store.Snapshot{TotalLOC: 2000, TotalComplexity: 50})
+	if !ok || v != 25 {
+		t.Errorf("Value() = %v, %v; want 25, true", v, ok)
+	}
+	if m.Format(v) != "25.0" {
+		t.Errorf("Format(%v) = %q", v, m.Format(v))
+	}
+
+	if _, ok := m.Value(store.Snapshot{TotalLOC: 2000}); ok {
+		t.Error("expected no value for snapshot without complexity")
+	}
+}
+
+func TestFormatters(t *testing.T) {
+	tests := []struct {
+		metric string
+		value  float64
+		want   string
+	}{
+		{"bytes", 512, "512 B"},
+		{"bytes", 12_345, "12.3 kB"},
+		{"bytes", 4_500_000, "4.5 MB"},
+		{"dryness", 87.4, "87%"},
+		{"uloc", 12_345, "12.3k"},
+		{"cost", 950, "$950"},
+		{"cost", 1_234_567, "$1.2M"},
+		{"effort", 26.93, "26.9 person-months"},
+	}
+	for _, tt := range tests {
+		m, _ := Lookup(tt.metric)
+		if got := m.Format(tt.value); got != tt.want {
+			t.Errorf("%s Format(%v) = %q, want %q", tt.metric, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestDrynessValue(t *testing.T) {
+	m, _ := Lookup("dryness")
+
+	v, ok := m.Value(store.Snapshot{Dryness: 0.75})
+	if !ok || v != 75 {
+		t.Errorf("Value() = %v, %v; want 75, true", v, ok)
+	}
+}
+
+func TestCostValue(t *testing.T) {
+	m, _ := Lookup("cost")
+
+	if _, ok := m.Value(store.Snapshot{TotalLOC: 100}); ok {
+		t.Error("expected no value for snapshot without a COCOMO estimate")
+	}
+	v, ok := m.Value(store.Snapshot{Cocomo: &store.CocomoRecord{Cost: 1234}})
+	if !ok || v != 1234 {
+		t.Errorf("Value() = %v, %v; want 1234, true", v, ok)
+	}
+}
+
+func TestTestRatioValue(t *testing.T) {
+	m, _ := Lookup("test-ratio")
+
+	testCode, noTests := int64(500), int64(0)
+	v, ok := m.Value(store.Snapshot{TotalLOC: 1500, TotalTestCode: &testCode})
+	if !ok || v != 0.5 {
+		t.Errorf("Value() = %v, %v; want 0.5, true", v, ok)
+	}
+	if m.Format(v) != "0.50" {
+		t.Errorf("Format(%v) = %q", v, m.Format(v))
+	}
+	if _, ok := m.Value(store.Snapshot{TotalLOC: 1500}); ok {
+		t.Error("expected no value for snapshot that predates the test/production split")
+	}
+	if v, ok := m.Value(store.Snapshot{TotalLOC: 1500, TotalTestCode: &noTests}); !ok || v != 0 {
+		t.Errorf("no tests: Value() = %v, %v; want 0, true", v, ok)
+	}
+}
+
+func TestCommentDensityValue(t *testing.T) {
+	m, _ := Lookup("comment-density")
+
+	v, ok := m.Value(store.Snapshot{Languages: []store.LanguageRecord{
+		{Language: "Go", Code: 700, Comments: 200},
+		{Language: "Python", Code: 100, Comments: 0},
+	}})
+	if !ok || v != 20 {
+		t.Errorf("Value() = %v, %v; want 20, true", v, ok)
+	}
+	if _, ok := m.Value(store.Snapshot{}); ok {
+		t.Error("expected no value for snapshot without languages")
+	}
+}
+
+func TestBlankRatioValue(t *testing.T) {
+	m, _ := Lookup("blank-ratio")
+
+	v, ok := m.Value(store.Snapshot{Languages: []store.LanguageRecord{{Language: "Go", Lines: 400, Blanks: 100}}})
+	if !ok || v != 25 {
+		t.Errorf("Value() = %v, %v; want 25, true", v, ok)
+	}
+}
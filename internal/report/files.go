@@ -0,0 +1,82 @@
+package report
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/rjwalters/ghloc/internal/store"
+)
+
+// FileGrowth describes how a file's code lines changed between two snapshots.
+type FileGrowth struct {
+	Path     string
+	Language string
+	Before   int64
+	After    int64
+	Delta    int64
+}
+
+// Baseline returns the newest snapshot in history that has per-file records
+// and whose counts differ from current, or nil if there is none. Snapshots
+// with the same counts as current are skipped: they were usually taken of the
+// same tree, as when CI records a snapshot before the report runs, and
+// comparing against them would show no growth.
+func Baseline(history []store.Snapshot, current store.Snapshot) *store.Snapshot {
+	var base *store.Snapshot
+	for i := range history {
+		s := &history[i]
+		if len(s.Files) == 0 || store.SameCounts(*s, current, 0) {
+			continue
+		}
+		if base == nil || s.CreatedAt.After(base.CreatedAt) {
+			base = s
+		}
+	}
+	return base
+}
+
+// LargestFiles returns up to n files ordered by code lines, largest first;
+// none when n <= 0.
+// Ties are broken by path so the report is stable.
+func LargestFiles(files []store.FileRecord, n int) []store.FileRecord {
+	sorted := slices.Clone(files)
+	slices.SortFunc(sorted, func(a, b store.FileRecord) int {
+		if c := cmp.Compare(b.Code, a.Code); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Path, b.Path)
+	})
+	return sorted[:min(max(n, 0), len(sorted))]
+}
+
+// GrowingFiles compares two sets of file records and returns up to n files whose
+// code lines increased the most. Files absent from prev count as grown from zero.
+func GrowingFiles(prev, cur []store.FileRecord, n int) []FileGrowth {
+	before := make(map[string]int64, len(prev))
+	for _, f := range prev {
+		before[f.Path] = f.Code
+	}
+
+	var grown []FileGrowth
+	for _, f := range cur {
+		delta := f.Code - before[f.Path]
+		if delta <= 0 {
+			continue
+		}
+		grown = append(grown, FileGrowth{
+			Path:     f.Path,
+			Language: f.Language,
+			Before:   before[f.Path],
+			After:    f.Code,
+			Delta:    delta,
+		})
+	}
+
+	slices.SortFunc(grown, func(a, b FileGrowth) int {
+		if c := cmp.Compare(b.Delta, a.Delta); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Path, b.Path)
+	})
+	return grown[:min(max(n, 0), len(grown))]
+}
@@ -0,0 +1,94 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rjwalters/ghloc/internal/store"
+)
+
+func TestLargestFiles(t *testing.T) {
+	files := []store.FileRecord{
+		{Path: "b.go", Code: 10},
+		{Path: "a.go", Code: 50},
+		{Path: "c.go", Code: 10},
+		{Path: "d.go", Code: 5},
+	}
+
+	got := LargestFiles(files, 3)
+
+	want := []string{"a.go", "b.go", "c.go"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d files, got %d", len(want), len(got))
+	}
+	for i, f := range got {
+		if f.Path != want[i] {
+			t.Errorf("file %d: got %q, want %q", i, f.Path, want[i])
+		}
+	}
+}
+
+func TestLargestFiles_FewerThanN(t *testing.T) {
+	got := LargestFiles([]store.FileRecord{{Path: "a.go", Code: 1}}, 10)
+	if len(got) != 1 {
+		t.Errorf("expected 1 file, got %d", len(got))
+	}
+}
+
+func TestFiles_NegativeN(t *testing.T) {
+	files := []store.FileRecord{{Path: "a.go", Code: 1}}
+	if got := LargestFiles(files, -1); len(got) != 0 {
+		t.Errorf("LargestFiles: expected no files, got %d", len(got))
+	}
+	if got := GrowingFiles(nil, files, -1); len(got) != 0 {
+		t.Errorf("GrowingFiles: expected no files, got %d", len(got))
+	}
+}
+
+func TestGrowingFiles(t *testing.T) {
+	prev := []store.FileRecord{
+		{Path: "grew.go", Code: 10},
+		{Path: "shrank.go", Code: 50},
+		{Path: "same.go", Code: 20},
+	}
+	cur := []store.FileRecord{
+		{Path: "grew.go", Code: 15},
+		{Path: "shrank.go", Code: 40},
+		{Path: "same.go", Code: 20},
+		{Path: "new.go", Code: 30, Language: "Go"},
+	}
+
+	got := GrowingFiles(prev, cur, 10)
+
+	if len(got) != 2 {
+		t.Fatalf("expected 2 grown files, got %d: %+v", len(got), got)
+	}
+	if got[0].Path != "new.go" || got[0].Delta != 30 || got[0].Before != 0 {
+		t.Errorf("first: got %+v, want new.go +30 from 0", got[0])
+	}
+	if got[1].Path != "grew.go" || got[1].Delta != 5 {
+		t.Errorf("second: got %+v, want grew.go +5", got[1])
+	}
+}
+
+func TestBaseline(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	files := []store.FileRecord{{Path: "a.go", Code: 1}}
+	history := []store.Snapshot{
+		{TotalLOC: 100, Files: files, CreatedAt: base.Add(2 * time.Hour)},
+		{TotalLOC: 50, Files: files, CreatedAt: base},
+		{TotalLOC: 120, CreatedAt: base.Add(3 * time.Hour)}, // no per-file records
+		{TotalLOC: 150, Files: files, CreatedAt: base.Add(4 * time.Hour)},
+	}
+
+	// The newest snapshot matches the live count, as in CI, so it is skipped
+	if got := Baseline(history, store.Snapshot{TotalLOC: 150}); got == nil || got.TotalLOC != 100 {
+		t.Errorf("Baseline() = %+v, want the 100-line snapshot", got)
+	}
+	if got := Baseline(history, store.Snapshot{TotalLOC: 200}); got == nil || got.TotalLOC != 150 {
+		t.Errorf("Baseline() = %+v, want the 150-line snapshot", got)
+	}
+	if got := Baseline(history[2:3], store.Snapshot{TotalLOC: 200}); got != nil {
+		t.Errorf("Baseline() = %+v, want nil without per-file records", got)
+	}
+}
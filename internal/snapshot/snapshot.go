@@ -0,0 +1,71 @@
+// Package snapshot converts counter results into history snapshots. It sits
+// between the counter and the store so the store does not depend on scc.
+package snapshot
+
+import (
+	"time"
+
+	"github.com/rjwalters/ghloc/internal/counter"
+	"github.com/rjwalters/ghloc/internal/store"
+)
+
+// FromResult converts a counter result into a snapshot taken at createdAt,
+// including per-file records.
+func FromResult(result *counter.LOCResult, createdAt time.Time) store.Snapshot {
+	testCode := result.TotalTestCode
+	snap := store.Snapshot{
+		TotalLOC:        result.TotalCode,
+		TotalFiles:      result.TotalFiles,
+		TotalComplexity: result.TotalComplexity,
+		TotalBytes:      result.TotalBytes,
+		TotalULOC:       result.TotalULOC,
+		Dryness:         result.Dryness(),
+		TotalTestCode:   &testCode,
+		CreatedAt:       createdAt,
+	}
+	snap.Languages = languageRecords(result.Languages)
+	for _, sub := range result.Submodules {
+		snap.Submodules = append(snap.Submodules, store.SubmoduleRecord{
+			Name:       sub.Name,
+			Path:       sub.Path,
+			TotalLOC:   sub.TotalCode,
+			TotalFiles: sub.TotalFiles,
+			Languages:  languageRecords(sub.Languages),
+		})
+	}
+	for _, f := range result.Files {
+		snap.Files = append(snap.Files, store.FileRecord{
+			Path:       f.Path,
+			Language:   f.Language,
+			Lines:      f.Lines,
+			Code:       f.Code,
+			Comments:   f.Comments,
+			Blanks:     f.Blanks,
+			Bytes:      f.Bytes,
+			Complexity: f.Complexity,
+			Test:       f.Test,
+		})
+	}
+	return snap
+}
+
+// languageRecords converts per-language counter stats into history records.
+func languageRecords(languages []counter.LanguageStats) []store.LanguageRecord {
+	var records []store.LanguageRecord
+	for _, lang := range languages {
+		records = append(records, store.LanguageRecord{
+			Language:   lang.Language,
+			Lines:      lang.Lines,
+			Code:       lang.Code,
+			Comments:   lang.Comments,
+			Blanks:     lang.Blanks,
+			Files:      lang.Files,
+			Complexity: lang.Complexity,
+			Bytes:      lang.Bytes,
+			ULOC:       lang.ULOC,
+			TestCode:   lang.TestCode,
+			TestFiles:  lang.TestFiles,
+		})
+	}
+	return records
+}
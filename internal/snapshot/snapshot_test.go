@@ -0,0 +1,23 @@
+package snapshot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rjwalters/ghloc/internal/counter"
+	"github.com/rjwalters/ghloc/internal/golden"
+	"github.com/rjwalters/ghloc/internal/store"
+)
+
+func TestFromResult_Golden(t *testing.T) {
+	result, err := counter.Count("testdata/repo", counter.Options{})
+	if err != nil {
+		t.Fatalf("Count() error: %v", err)
+	}
+	snap := FromResult(result, time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	got, err := store.MarshalHistory([]store.Snapshot{snap})
+	if err != nil {
+		t.Fatalf("MarshalHistory() error: %v", err)
+	}
+	golden.Check(t, "snapshot.json", got)
+}
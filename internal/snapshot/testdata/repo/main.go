@@ -0,0 +1,14 @@
+// Command greet prints a greeting.
+package main
+
+import "fmt"
+
+func main() {
+	if name := greeting(); name != "" {
+		fmt.Println(name)
+	}
+}
+
+func greeting() string {
+	return "hello"
+}
@@ -0,0 +1,9 @@
+package main
+
+import "testing"
+
+func TestGreeting(t *testing.T) {
+	if greeting() != "hello" {
+		t.Error("unexpected greeting")
+	}
+}
@@ -0,0 +1,101 @@
+package store
+
+import "math"
+
+// SameCounts reports whether b has the same counts as a, with each value
+// allowed to differ by the fraction tolerance of the larger one (0 requires
+// equality). It compares the total code lines, files, complexity, unique
+// lines and test code; per language, the lines, code, comments, blanks,
+// files, complexity, unique lines, test code and test files; and per
+// submodule, the code lines, files and languages. Everything else, such as
+// byte sizes, dryness, COCOMO estimates, per-file records and timestamps, is
+// ignored, so edits that only change the text of lines, such as typo fixes,
+// compare equal.
+func SameCounts(a, b Snapshot, tolerance float64) bool {
+	if !within(tolerance,
+		a.TotalLOC, b.TotalLOC,
+		a.TotalFiles, b.TotalFiles,
+		a.TotalComplexity, b.TotalComplexity,
+		a.TotalULOC, b.TotalULOC,
+		deref(a.TotalTestCode), deref(b.TotalTestCode),
+	) {
+		return false
+	}
+	if !sameLanguages(a.Languages, b.Languages, tolerance) || len(a.Submodules) != len(b.Submodules) {
+		return false
+	}
+
+	subs := make(map[string]SubmoduleRecord, len(a.Submodules))
+	for _, sub := range a.Submodules {
+		subs[sub.Path] = sub
+	}
+	for _, sb := range b.Submodules {
+		sa, ok := subs[sb.Path]
+		if !ok || !within(tolerance, sa.TotalLOC, sb.TotalLOC, sa.TotalFiles, sb.TotalFiles) ||
+			!sameLanguages(sa.Languages, sb.Languages, tolerance) {
+			return false
+		}
+	}
+	return true
+}
+
+// Latest returns the snapshot with the newest CreatedAt, or nil for an empty
+// history. Merged or hand-edited histories need not be in order. Of several
+// snapshots taken at the same time, the last one wins.
+func Latest(history []Snapshot) *Snapshot {
+	var latest *Snapshot
+	for i := range history {
+		if latest == nil || !history[i].CreatedAt.Before(latest.CreatedAt) {
+			latest = &history[i]
+		}
+	}
+	return latest
+}
+
+// sameLanguages compares per-language records regardless of their order.
+func sameLanguages(a, b []LanguageRecord, tolerance float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	byName := make(map[string]LanguageRecord, len(a))
+	for _, la := range a {
+		byName[la.Language] = la
+	}
+	for _, lb := range b {
+		la, ok := byName[lb.Language]
+		if !ok || !within(tolerance,
+			la.Lines, lb.Lines,
+			la.Code, lb.Code,
+			la.Comments, lb.Comments,
+			la.Blanks, lb.Blanks,
+			la.Files, lb.Files,
+			la.Complexity, lb.Complexity,
+			la.ULOC, lb.ULOC,
+			la.TestCode, lb.TestCode,
+			la.TestFiles, lb.TestFiles,
+		) {
+			return false
+		}
+	}
+	return true
+}
+
+// within reports whether each pair of values differs by at most the fraction
+// tolerance of the larger value.
+func within(tolerance float64, pairs ...int64) bool {
+	for i := 0; i+1 < len(pairs); i += 2 {
+		x, y := float64(pairs[i]), float64(pairs[i+1])
+		if math.Abs(x-y) > tolerance*math.Max(math.Abs(x), math.Abs(y)) {
+			return false
+		}
+	}
+	return true
+}
+
+// deref returns *p, or 0 for a count the snapshot did not record.
+func deref(p *int64) int64 {
+	if p == nil {
+		return 0
+	}
+	return *p
+}
@@ -0,0 +1,87 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSameCounts(t *testing.T) {
+	base := Snapshot{
+		TotalLOC:   1000,
+		TotalFiles: 10,
+		TotalBytes: 40000,
+		Languages: []LanguageRecord{
+			{Language: "Go", Lines: 900, Code: 800, Comments: 50, Blanks: 50, Files: 8},
+			{Language: "Markdown", Lines: 200, Code: 200, Files: 2, Bytes: 6000},
+		},
+		Submodules: []SubmoduleRecord{{Name: "lib", Path: "vendor/lib", TotalLOC: 100, TotalFiles: 1}},
+		CreatedAt:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	edit := func(f func(s *Snapshot)) Snapshot {
+		s := base
+		s.Languages = append([]LanguageRecord(nil), base.Languages...)
+		s.Submodules = append([]SubmoduleRecord(nil), base.Submodules...)
+		f(&s)
+		return s
+	}
+
+	tests := []struct {
+		name      string
+		next      Snapshot
+		tolerance float64
+		want      bool
+	}{
+		{"identical", base, 0, true},
+		{"typo fix changes only bytes and time", edit(func(s *Snapshot) {
+			s.TotalBytes++
+			s.Languages[1].Bytes++
+			s.CreatedAt = s.CreatedAt.Add(time.Hour)
+		}), 0, true},
+		{"languages reordered", edit(func(s *Snapshot) {
+			s.Languages[0], s.Languages[1] = s.Languages[1], s.Languages[0]
+		}), 0, true},
+		{"one more line", edit(func(s *Snapshot) {
+			s.TotalLOC++
+			s.Languages[0].Code++
+			s.Languages[0].Lines++
+		}), 0, false},
+		{"one more line within tolerance", edit(func(s *Snapshot) {
+			s.TotalLOC++
+			s.Languages[0].Code++
+			s.Languages[0].Lines++
+		}), 0.01, true},
+		{"comment moved between languages", edit(func(s *Snapshot) {
+			s.Languages[0].Comments -= 10
+			s.Languages[0].Code += 10
+		}), 0, false},
+		{"new language", edit(func(s *Snapshot) {
+			s.Languages = append(s.Languages, LanguageRecord{Language: "Python", Lines: 1, Code: 1, Files: 1})
+		}), 0.5, false},
+		{"submodule changed", edit(func(s *Snapshot) {
+			s.Submodules[0].TotalLOC = 200
+		}), 0.1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SameCounts(base, tt.next, tt.tolerance); got != tt.want {
+				t.Errorf("SameCounts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLatest(t *testing.T) {
+	if Latest(nil) != nil {
+		t.Error("Latest(nil) should be nil")
+	}
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	history := []Snapshot{
+		{TotalLOC: 1, CreatedAt: jan},
+		{TotalLOC: 3, CreatedAt: jan.AddDate(0, 2, 0)}, // merged out of order
+		{TotalLOC: 2, CreatedAt: jan.AddDate(0, 1, 0)},
+		{TotalLOC: 4, CreatedAt: jan.AddDate(0, 2, 0)},
+	}
+	if got := Latest(history); got != &history[3] {
+		t.Errorf("Latest() = %+v, want the last of the newest snapshots", got)
+	}
+}
@@ -1,6 +1,7 @@
 package store
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -8,7 +9,8 @@ import (
 )
 
 // LoadHistory reads a slice of Snapshots from a JSON file.
-// Returns an empty slice if the file does not exist.
+// Returns an empty slice if the file does not exist or is empty, as git's
+// ancestor file is for an add/add merge conflict.
 func LoadHistory(path string) ([]Snapshot, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -17,6 +19,9 @@ func LoadHistory(path string) ([]Snapshot, error) {
 		}
 		return nil, fmt.Errorf("read history: %w", err)
 	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
 
 	var snapshots []Snapshot
 	if err := json.Unmarshal(data, &snapshots); err != nil {
@@ -31,9 +36,9 @@ func SaveHistory(path string, snapshots []Snapshot) error {
 		return fmt.Errorf("create dir: %w", err)
 	}
 
-	data, err := json.MarshalIndent(snapshots, "", "  ")
+	data, err := MarshalHistory(snapshots)
 	if err != nil {
-		return fmt.Errorf("marshal history: %w", err)
+		return err
 	}
 
 	if err := os.WriteFile(path, data, 0644); err != nil {
@@ -41,3 +46,12 @@ func SaveHistory(path string, snapshots []Snapshot) error {
 	}
 	return nil
 }
+
+// MarshalHistory encodes snapshots exactly as SaveHistory writes them.
+func MarshalHistory(snapshots []Snapshot) ([]byte, error) {
+	data, err := json.MarshalIndent(snapshots, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal history: %w", err)
+	}
+	return data, nil
+}
@@ -5,6 +5,8 @@ import (
 	"path/filepath"
 	"testing"
 	"time"
+
+	"github.com/rjwalters/ghloc/internal/golden"
 )
 
 func TestLoadHistory_NoFile(t *testing.T) {
@@ -17,6 +19,21 @@ func TestLoadHistory_NoFile(t *testing.T) {
 	}
 }
 
+func TestLoadHistory_Empty(t *testing.T) {
+	for _, content := range []string{"", " \n\t\n"} {
+		path := filepath.Join(t.TempDir(), "history.json")
+		os.WriteFile(path, []byte(content), 0644)
+
+		snapshots, err := LoadHistory(path)
+		if err != nil {
+			t.Fatalf("LoadHistory(%q) error: %v", content, err)
+		}
+		if snapshots != nil {
+			t.Errorf("LoadHistory(%q) = %v, want nil", content, snapshots)
+		}
+	}
+}
+
 func TestSaveAndLoadHistory(t *testing.T) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, "subdir", "history.json")
@@ -106,3 +123,67 @@ func TestLoadHistory_InvalidJSON(t *testing.T) {
 		t.Fatal("expected error for invalid JSON")
 	}
 }
+
+func TestMarshalHistory_Golden(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	testCode := int64(400)
+	snapshots := []Snapshot{
+		{
+			TotalLOC: 969, TotalFiles: 12, TotalComplexity: 88, TotalBytes: 31250, TotalULOC: 1120, Dryness: 0.8235294117647058,
+			Languages: []LanguageRecord{
+				{Language: "Go", Lines: 1100, Code: 900, Comments: 80, Blanks: 120, Files: 10, Complexity: 88, Bytes: 30000, ULOC: 1050},
+				{Language: "Markdown", Lines: 90, Code: 69, Blanks: 21, Files: 2, Bytes: 1250, ULOC: 70},
+			},
+			CreatedAt: base,
+		},
+		{
+			TotalLOC: 1520, TotalFiles: 15, TotalTestCode: &testCode,
+			Cocomo:    &CocomoRecord{ProjectType: "organic", Cost: 41234.56, EffortMonths: 3.7, ScheduleMonths: 3.9, People: 0.95},
+			Languages: []LanguageRecord{{Language: "Go", Lines: 1800, Code: 1520, Files: 15, TestCode: 400, TestFiles: 4}},
+			Files:     []FileRecord{{Path: "main.go", Language: "Go", Lines: 50, Code: 40, Test: false}},
+			Submodules: []SubmoduleRecord{
+				{Name: "lib", Path: "vendor/lib", TotalLOC: 120, TotalFiles: 1, Languages: []LanguageRecord{{Language: "C", Code: 120, Files: 1}}},
+			},
+			CreatedAt: base.AddDate(0, 1, 0),
+		},
+	}
+
+	data, err := MarshalHistory(snapshots)
+	if err != nil {
+		t.Fatalf("MarshalHistory() error: %v", err)
+	}
+	golden.Check(t, "history.json", data)
+
+	// Loading and saving unchanged history must reproduce the file exactly
+	path := filepath.Join(t.TempDir(), "history.json")
+	os.WriteFile(path, data, 0644)
+	loaded, err := LoadHistory(path)
+	if err != nil {
+		t.Fatalf("LoadHistory() error: %v", err)
+	}
+	again, _ := MarshalHistory(loaded)
+	if string(again) != string(data) {
+		t.Error("history.json changed after a load and save round trip")
+	}
+}
+
+func TestMarshalHistory_ZeroTestCode(t *testing.T) {
+	// A recorded zero must survive a round trip, unlike a missing value
+	noTests := int64(0)
+	data, err := MarshalHistory([]Snapshot{{TotalLOC: 10, TotalTestCode: &noTests}, {TotalLOC: 10}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "history.json")
+	os.WriteFile(path, data, 0644)
+	loaded, err := LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded[0].TotalTestCode == nil || *loaded[0].TotalTestCode != 0 {
+		t.Errorf("recorded zero: got %v", loaded[0].TotalTestCode)
+	}
+	if loaded[1].TotalTestCode != nil {
+		t.Errorf("unrecorded: got %v, want nil", *loaded[1].TotalTestCode)
+	}
+}
@@ -0,0 +1,33 @@
+package store
+
+import (
+	"slices"
+)
+
+// MergeHistory unions several histories into one chronologically sorted slice.
+// Snapshots sharing the same CreatedAt are treated as the same measurement and
+// kept once, as Normalize does: the last occurrence wins, so callers should
+// pass the most authoritative history last.
+func MergeHistory(histories ...[]Snapshot) []Snapshot {
+	return Normalize(slices.Concat(histories...))
+}
+
+// Normalize returns a copy of history sorted by CreatedAt, keeping only the
+// last snapshot recorded for any given timestamp. Latest picks the same
+// snapshot of a tie, so charts and comparisons agree on it.
+func Normalize(history []Snapshot) []Snapshot {
+	sorted := slices.Clone(history)
+	slices.SortStableFunc(sorted, func(a, b Snapshot) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	out := sorted[:0]
+	for _, s := range sorted {
+		if len(out) > 0 && out[len(out)-1].CreatedAt.Equal(s.CreatedAt) {
+			out[len(out)-1] = s
+			continue
+		}
+		out = append(out, s)
+	}
+	return out
+}
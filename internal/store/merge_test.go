@@ -0,0 +1,107 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMergeHistory_UnionAndSort(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	ancestor := []Snapshot{
+		{TotalLOC: 100, CreatedAt: base},
+	}
+	ours := []Snapshot{
+		{TotalLOC: 100, CreatedAt: base},
+		{TotalLOC: 300, CreatedAt: base.Add(2 * day)},
+	}
+	theirs := []Snapshot{
+		{TotalLOC: 100, CreatedAt: base},
+		{TotalLOC: 200, CreatedAt: base.Add(day)},
+	}
+
+	merged := MergeHistory(ancestor, ours, theirs)
+
+	if len(merged) != 3 {
+		t.Fatalf("expected 3 snapshots, got %d", len(merged))
+	}
+	want := []int64{100, 200, 300}
+	for i, s := range merged {
+		if s.TotalLOC != want[i] {
+			t.Errorf("snapshot %d TotalLOC: got %d, want %d", i, s.TotalLOC, want[i])
+		}
+	}
+}
+
+func TestMergeHistory_LastOccurrenceWins(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	merged := MergeHistory(
+		[]Snapshot{{TotalLOC: 1, CreatedAt: base}},
+		[]Snapshot{{TotalLOC: 2, CreatedAt: base}, {TotalLOC: 3, CreatedAt: base}},
+	)
+
+	if len(merged) != 1 {
+		t.Fatalf("expected 1 snapshot, got %d", len(merged))
+	}
+	if merged[0].TotalLOC != 3 {
+		t.Errorf("TotalLOC: got %d, want 3", merged[0].TotalLOC)
+	}
+}
+
+func TestNormalize_AgreesWithLatest(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	history := []Snapshot{
+		{TotalLOC: 2, CreatedAt: base.Add(time.Hour)},
+		{TotalLOC: 1, CreatedAt: base},
+		{TotalLOC: 3, CreatedAt: base.Add(time.Hour)},
+	}
+
+	normalized := Normalize(history)
+	if len(normalized) != 2 || normalized[0].TotalLOC != 1 || normalized[1].TotalLOC != 3 {
+		t.Errorf("Normalize() = %+v, want LOC 1 then 3", normalized)
+	}
+	if latest := Latest(history); latest.TotalLOC != normalized[len(normalized)-1].TotalLOC {
+		t.Errorf("Latest() = %d, want the normalized history's last snapshot", latest.TotalLOC)
+	}
+	if history[0].TotalLOC != 2 {
+		t.Error("Normalize() modified its input")
+	}
+}
+
+func TestMergeHistory_Empty(t *testing.T) {
+	if merged := MergeHistory(nil, nil); len(merged) != 0 {
+		t.Errorf("expected no snapshots, got %d", len(merged))
+	}
+}
+
+func TestMergeHistory_EmptyAncestorFile(t *testing.T) {
+	// Git passes an empty %O when both branches added history.json
+	dir := t.TempDir()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	paths := map[string][]Snapshot{
+		"ours.json":   {{TotalLOC: 100, CreatedAt: base}},
+		"theirs.json": {{TotalLOC: 200, CreatedAt: base.Add(time.Hour)}},
+	}
+	for name, snapshots := range paths {
+		if err := SaveHistory(filepath.Join(dir, name), snapshots); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.WriteFile(filepath.Join(dir, "ancestor.json"), nil, 0644)
+
+	var loaded [][]Snapshot
+	for _, name := range []string{"ours.json", "theirs.json", "ancestor.json"} {
+		snapshots, err := LoadHistory(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("LoadHistory(%s) error: %v", name, err)
+		}
+		loaded = append(loaded, snapshots)
+	}
+	if merged := MergeHistory(loaded...); len(merged) != 2 {
+		t.Errorf("expected 2 snapshots, got %d", len(merged))
+	}
+}
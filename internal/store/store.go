@@ -3,19 +3,66 @@ package store
 import "time"
 
 // Snapshot represents a single LOC measurement at a point in time.
+// Metrics added after the first release are zero (or nil) in older snapshots.
 type Snapshot struct {
-	TotalLOC   int64            `json:"total_loc"`
-	TotalFiles int64            `json:"total_files"`
-	Languages  []LanguageRecord `json:"languages"`
-	CreatedAt  time.Time        `json:"created_at"`
+	TotalLOC        int64             `json:"total_loc"`
+	TotalFiles      int64             `json:"total_files"`
+	TotalComplexity int64             `json:"total_complexity,omitempty"` // summed cyclomatic complexity
+	TotalBytes      int64             `json:"total_bytes,omitempty"`
+	TotalULOC       int64             `json:"total_uloc,omitempty"`      // unique lines across the repository
+	Dryness         float64           `json:"dryness,omitempty"`         // TotalULOC divided by total lines
+	TotalTestCode   *int64            `json:"total_test_code,omitempty"` // code lines in test files; nil if not recorded
+	Cocomo          *CocomoRecord     `json:"cocomo,omitempty"`
+	Languages       []LanguageRecord  `json:"languages"`
+	Files           []FileRecord      `json:"files,omitempty"`
+	Submodules      []SubmoduleRecord `json:"submodules,omitempty"`
+	CreatedAt       time.Time         `json:"created_at"`
 }
 
 // LanguageRecord stores LOC for a single language within a snapshot.
 type LanguageRecord struct {
-	Language string `json:"language"`
-	Lines    int64  `json:"lines"`
-	Code     int64  `json:"code"`
-	Comments int64  `json:"comments"`
-	Blanks   int64  `json:"blanks"`
-	Files    int64  `json:"files"`
+	Language   string `json:"language"`
+	Lines      int64  `json:"lines"`
+	Code       int64  `json:"code"`
+	Comments   int64  `json:"comments"`
+	Blanks     int64  `json:"blanks"`
+	Files      int64  `json:"files"`
+	Complexity int64  `json:"complexity,omitempty"`
+	Bytes      int64  `json:"bytes,omitempty"`
+	ULOC       int64  `json:"uloc,omitempty"`
+	TestCode   int64  `json:"test_code,omitempty"` // Code - TestCode is production code
+	TestFiles  int64  `json:"test_files,omitempty"`
+}
+
+// FileRecord stores LOC for a single file within a snapshot. Per-file records
+// are optional and can be numerous, so they use short JSON keys and omit zero
+// values to keep history.json compact.
+type FileRecord struct {
+	Path       string `json:"p"`
+	Language   string `json:"l,omitempty"`
+	Lines      int64  `json:"n,omitempty"`
+	Code       int64  `json:"c,omitempty"`
+	Comments   int64  `json:"m,omitempty"`
+	Blanks     int64  `json:"b,omitempty"`
+	Bytes      int64  `json:"s,omitempty"`
+	Complexity int64  `json:"x,omitempty"`
+	Test       bool   `json:"t,omitempty"`
+}
+
+// CocomoRecord stores a Basic COCOMO estimate computed for a snapshot.
+type CocomoRecord struct {
+	ProjectType    string  `json:"project_type"`
+	Cost           float64 `json:"cost"`
+	EffortMonths   float64 `json:"effort_months"`
+	ScheduleMonths float64 `json:"schedule_months"`
+	People         float64 `json:"people"`
+}
+
+// SubmoduleRecord stores the LOC attributed to a git submodule within a snapshot.
+type SubmoduleRecord struct {
+	Name       string           `json:"name"`
+	Path       string           `json:"path"`
+	TotalLOC   int64            `json:"total_loc"`
+	TotalFiles int64            `json:"total_files"`
+	Languages  []LanguageRecord `json:"languages"`
 }
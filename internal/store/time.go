@@ -0,0 +1,20 @@
+package store
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// SnapshotTime returns the time to record for a new snapshot: now, or for
+// reproducible output, epoch, the Unix time from SOURCE_DATE_EPOCH, if set.
+func SnapshotTime(epoch string, now time.Time) (time.Time, error) {
+	if epoch == "" {
+		return now.UTC(), nil
+	}
+	sec, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("SOURCE_DATE_EPOCH: %w", err)
+	}
+	return time.Unix(sec, 0).UTC(), nil
+}
@@ -0,0 +1,26 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSnapshotTime(t *testing.T) {
+	now := time.Date(2024, 6, 1, 14, 0, 0, 0, time.FixedZone("CEST", 2*3600))
+	tests := []struct {
+		epoch string
+		want  time.Time
+	}{
+		{"", time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)},
+		{"1700000000", time.Date(2023, 11, 14, 22, 13, 20, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := SnapshotTime(tt.epoch, now)
+		if err != nil || !got.Equal(tt.want) || got.Location() != time.UTC {
+			t.Errorf("SnapshotTime(%q) = %v, %v, want %v", tt.epoch, got, err, tt.want)
+		}
+	}
+	if _, err := SnapshotTime("yesterday", now); err == nil {
+		t.Error("expected error for a non-numeric SOURCE_DATE_EPOCH")
+	}
+}
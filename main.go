@@ -1,85 +1,448 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/narqo/go-badge"
+	"github.com/rjwalters/ghloc/internal/artifact"
 	"github.com/rjwalters/ghloc/internal/chart"
+	"github.com/rjwalters/ghloc/internal/cocomo"
+	"github.com/rjwalters/ghloc/internal/config"
 	"github.com/rjwalters/ghloc/internal/counter"
+	"github.com/rjwalters/ghloc/internal/metric"
+	"github.com/rjwalters/ghloc/internal/snapshot"
 	"github.com/rjwalters/ghloc/internal/store"
 
 	locbadge "github.com/rjwalters/ghloc/internal/badge"
 )
 
+// logLevel is raised by -v and -debug; warnings and errors are always shown.
+var logLevel = func() *slog.LevelVar {
+	var v slog.LevelVar
+	v.Set(slog.LevelWarn)
+	return &v
+}()
+
+// logger reports warnings and errors, and progress when asked to, on stderr.
+var logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel}))
+
+// fatal logs an error and exits.
+func fatal(msg string, args ...any) {
+	logger.Error(msg, args...)
+	os.Exit(1)
+}
+
 func main() {
-	dir := flag.String("dir", ".", "directory to count")
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "merge-driver":
+			runMergeDriver(os.Args[2:])
+			return
+		case "install-merge-driver":
+			runInstallMergeDriver(os.Args[2:])
+			return
+		case "top":
+			runTop(os.Args[2:])
+			return
+		}
+	}
+
+	dir := flag.String("dir", ".", "directory or archive (.tar, .tar.gz, .tgz, .zip) to count")
+	rev := flag.String("rev", "", "count the tree of this git revision of the repository at -dir instead of the working tree")
+	archivePath := flag.String("archive", "", "count this tar, tar.gz or zip archive instead of -dir")
+	maxFileSize := flag.Int64("max-file-size", 0, "skip files larger than this many bytes (default 10 MB); negative disables the cap")
+	maxLineLength := flag.Int("max-avg-line-length", 0, "skip files, usually minified, whose average line is longer than this many bytes (default 255); negative disables the check")
 	output := flag.String("output", ".ghloc", "output directory for artifacts")
+	chartGap := flag.Duration("chart-gap", 0, "break the chart line across gaps longer than this (e.g. 720h); 0 disables")
+	sparklinePoints := flag.Int("sparkline-points", 30, "number of recent snapshots shown in sparkline.svg and badge-sparkline.svg; 0 shows all")
+	growthPeriod := flag.String("growth-period", string(chart.Monthly), "bucket growth.svg by week or month")
+	growthAverage := flag.Int("growth-average", 0, "overlay a rolling average over this many periods on growth.svg; 0 or 1 disables")
+	withFiles := flag.Bool("files", false, "persist per-file records in history.json")
+	extraCharts := flag.String("charts", "", "comma-separated metrics to chart as chart-<metric>.svg (available: "+strings.Join(metric.Names(), ", ")+")")
+	extraBadges := flag.String("badges", "", "comma-separated metrics to render as badge-<metric>.svg")
+	configPath := flag.String("config", "", "path to config file (default: <output>/config.json)")
+	followSymlinks := flag.Bool("follow-symlinks", false, "count the targets of symbolic links")
+	submodules := flag.String("submodules", "", "how to count git submodules: include, exclude or separate (default include)")
+	codeOnly := flag.Bool("code-only", false, "count only programming languages toward totals and badges, not markup, data, prose or config")
+	tolerance := flag.Float64("tolerance", 0, "skip the snapshot when every total and per-language count is within this fraction of the last one (e.g. 0.001); 0 skips only identical counts")
+	force := flag.Bool("force", false, "add a snapshot even if the counts have not changed")
+	dryRun := flag.Bool("dry-run", false, "render everything and report which artifacts would change, without writing")
+	timeout := flag.Duration("timeout", 0, "give up if counting takes longer than this (e.g. 5m); 0 means no limit")
+	useCache := flag.Bool("cache", true, "reuse per-file results for unchanged files from <output>/cache")
+	verbose := flag.Bool("v", false, "log progress such as cache hit rates to stderr")
+	debug := flag.Bool("debug", false, "log how every file was classified to stderr")
+	explainPath := flag.String("explain", "", "explain how the file at this path, relative to -dir, is detected and counted, then exit")
+	testPatterns := flag.String("test-patterns", "", "comma-separated globs identifying test files (default: common conventions per language)")
+	cocomoParams := cocomo.DefaultParams()
+	flag.Float64Var(&cocomoParams.AverageWage, "cocomo-wage", cocomoParams.AverageWage, "average yearly wage for the COCOMO cost estimate")
+	flag.Float64Var(&cocomoParams.Overhead, "cocomo-overhead", cocomoParams.Overhead, "overhead multiplier for the COCOMO cost estimate")
+	cocomoType := flag.String("cocomo-type", string(cocomo.Organic), "COCOMO project type: organic, semi-detached or embedded")
 	flag.Parse()
 
-	log.SetFlags(0)
+	chartMetrics, err := metric.ParseList(*extraCharts)
+	if err != nil {
+		fatal("-charts", "err", err)
+	}
+	badgeMetrics, err := metric.ParseList(*extraBadges)
+	if err != nil {
+		fatal("-badges", "err", err)
+	}
+	density, err := metric.Lookup("comment-density") // graded on badge-docs.svg
+	if err != nil {
+		fatal("docs badge", "err", err)
+	}
+	cocomoParams.ProjectType, err = cocomo.ParseProjectType(*cocomoType)
+	if err != nil {
+		fatal("-cocomo-type", "err", err)
+	}
+	period, err := chart.ParsePeriod(*growthPeriod)
+	if err != nil {
+		fatal("-growth-period", "err", err)
+	}
+
+	if *verbose {
+		logLevel.Set(slog.LevelInfo)
+	}
+	if *debug {
+		logLevel.Set(slog.LevelDebug)
+	}
+
+	// Interrupting the count exits before anything is written. Once writing
+	// has begun, signals are ignored until it finishes.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	countCtx := ctx
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		countCtx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
 
 	// 1. Count LOC
-	result, err := counter.Count(*dir)
+	cfg := loadConfig(*configPath, *output)
+	countOpts := cfg.CounterOptions()
+	countOpts.Logger = logger
+	if *testPatterns != "" {
+		countOpts.TestPatterns = splitList(*testPatterns)
+	}
+	if *codeOnly {
+		countOpts.Languages.CodeOnly = true
+	}
+	if *followSymlinks {
+		countOpts.FollowSymlinks = true
+	}
+	if *maxFileSize != 0 {
+		countOpts.MaxFileSize = *maxFileSize
+	}
+	if *maxLineLength != 0 {
+		countOpts.MaxAverageLineLength = *maxLineLength
+	}
+	if *submodules != "" {
+		countOpts.Submodules, err = counter.ParseSubmoduleMode(*submodules)
+		if err != nil {
+			fatal("-submodules", "err", err)
+		}
+	}
+	source := *dir
+	if *archivePath != "" {
+		if *rev != "" {
+			fatal("-archive and -rev cannot be combined")
+		}
+		source = *archivePath
+	}
+	excludeOutput(&countOpts, source, *output)
+	if *explainPath != "" {
+		if *rev != "" {
+			fatal("-explain cannot be combined with -rev")
+		}
+		e, err := explainSource(source, *explainPath, countOpts)
+		if err != nil {
+			fatal("explain", "err", err)
+		}
+		printExplanation(os.Stdout, e)
+		return
+	}
+	var cache *counter.Cache
+	if *useCache {
+		cache, err = counter.OpenCache(filepath.Join(*output, "cache"))
+		if err != nil {
+			fatal("open cache", "err", err)
+		}
+		countOpts.Cache = cache
+	}
+	result, err := countSource(countCtx, source, *rev, countOpts)
 	if err != nil {
-		log.Fatalf("count: %v", err)
+		// An interrupted count is not saved to the cache or history
+		fatal("count", "err", err)
 	}
 	fmt.Printf("Counted %d lines of code across %d files (%d languages)\n",
 		result.TotalCode, result.TotalFiles, len(result.Languages))
+	for _, sub := range result.Submodules {
+		fmt.Printf("  submodule %s: %d lines of code across %d files\n", sub.Path, sub.TotalCode, sub.TotalFiles)
+	}
+	printSkipped(result)
+	if cache != nil && !*dryRun {
+		if err := cache.Save(); err != nil {
+			fatal("save cache", "err", err)
+		}
+		hits, misses := cache.Stats()
+		logger.Info("cache", "hits", hits, "misses", misses, "hit_rate", fmt.Sprintf("%.0f%%", cache.HitRate()*100))
+	}
 
 	// 2. Load existing history
 	historyPath := filepath.Join(*output, "history.json")
 	history, err := store.LoadHistory(historyPath)
 	if err != nil {
-		log.Fatalf("load history: %v", err)
+		fatal("load history", "err", err)
+	}
+
+	// 3. Append new snapshot, unless the counts match the latest one
+	createdAt, err := store.SnapshotTime(os.Getenv("SOURCE_DATE_EPOCH"), time.Now())
+	if err != nil {
+		fatal("snapshot time", "err", err)
+	}
+	snap := snapshot.FromResult(result, createdAt)
+	estimate, err := cocomo.FromResult(result, cocomoParams)
+	if err != nil {
+		fatal("cocomo", "err", err)
+	}
+	snap.Cocomo = &store.CocomoRecord{
+		ProjectType:    string(cocomoParams.ProjectType),
+		Cost:           estimate.Cost,
+		EffortMonths:   estimate.EffortMonths,
+		ScheduleMonths: estimate.ScheduleMonths,
+		People:         estimate.People,
+	}
+	if !*withFiles {
+		snap.Files = nil
+	}
+	appended := true
+	if latest := store.Latest(history); latest != nil && !*force && store.SameCounts(*latest, snap, *tolerance) {
+		snap, appended = *latest, false
+		fmt.Printf("Counts unchanged since %s; not adding a snapshot\n", snap.CreatedAt.Format(time.DateOnly))
+	} else {
+		history = append(history, snap)
+	}
+
+	// 4. Render every artifact before touching the output directory, so a
+	// failure leaves the previous set intact
+	artifacts := artifact.New(*output)
+	artifacts.Add("badge.svg", locbadge.RenderSVG(locbadge.FormatLOC(snap.TotalLOC), badge.ColorBlue))
+	artifacts.Add("chart.svg", chart.RenderHistoryChart(history, chart.Options{GapThreshold: *chartGap}))
+	artifacts.Add("growth.svg", chart.RenderGrowthChart(history, chart.Options{GrowthPeriod: period, RollingAverage: *growthAverage}))
+	artifacts.Add("sparkline.svg", chart.RenderSparkline(history, *sparklinePoints))
+	artifacts.Add("badge-sparkline.svg", chart.RenderSparklineBadge(history, *sparklinePoints))
+	artifacts.Add("languages-share.svg", chart.RenderLanguageDonut(snap, chart.Options{}))
+	artifacts.Add("languages-bar.svg", chart.RenderLanguageBar(snap, chart.Options{}))
+	artifacts.Add("comment-density.svg", chart.RenderCommentDensityChart(history, chart.Options{}))
+	if v, ok := density.Value(snap); ok {
+		artifacts.Add("badge-docs.svg", locbadge.RenderGradedSVG("docs", density.Format(v), v, cfg.DocThresholds()))
+	}
+	for _, m := range badgeMetrics {
+		v, ok := m.Value(snap)
+		if !ok {
+			logger.Warn("skipping badge: no value in current snapshot", "metric", m.Name)
+			continue
+		}
+		artifacts.Add("badge-"+m.Name+".svg", locbadge.RenderLabeledSVG(m.Label, m.Format(v), badge.ColorBlue))
+	}
+	for _, m := range chartMetrics {
+		artifacts.Add("chart-"+m.Name+".svg", chart.RenderMetricChart(history, m, chart.Options{GapThreshold: *chartGap}))
+	}
+	if appended {
+		historyJSON, err := store.MarshalHistory(history)
+		if err != nil {
+			fatal("marshal history", "err", err)
+		}
+		artifacts.Add("history.json", historyJSON)
+	}
+
+	if *dryRun {
+		changes, err := artifacts.Changes()
+		if err != nil {
+			fatal("compare artifacts", "err", err)
+		}
+		printChanges(changes, true)
+		reportChanged(changes)
+		return
+	}
+
+	if ctx.Err() != nil {
+		fatal("interrupted; nothing written", "cause", context.Cause(ctx))
+	}
+
+	// 5. Write them all at once
+	changes, err := artifacts.Commit()
+	if err != nil {
+		fatal("write artifacts", "err", err)
+	}
+	printChanges(changes, false)
+	fmt.Printf("History has %d snapshots\n", len(history))
+	reportChanged(changes)
+}
+
+// reportChanged tells a GitHub Actions workflow whether any artifact changed,
+// through the step output "changed".
+func reportChanged(changes []artifact.Change) {
+	changed := slices.ContainsFunc(changes, func(c artifact.Change) bool { return c.Status != artifact.Unchanged })
+	if !changed {
+		fmt.Println("No artifacts changed")
+	}
+	path := os.Getenv("GITHUB_OUTPUT")
+	if path == "" {
+		return
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		fatal("write step output", "err", err)
+	}
+	defer f.Close()
+	if _, err := fmt.Fprintf(f, "changed=%t\n", changed); err != nil {
+		fatal("write step output", "err", err)
+	}
+}
+
+// printChanges reports the effect of writing each artifact, or for a dry
+// run, the effect it would have.
+func printChanges(changes []artifact.Change, dryRun bool) {
+	created, updated := "Created", "Updated"
+	if dryRun {
+		created, updated = "Would create", "Would update"
+	}
+	for _, c := range changes {
+		switch c.Status {
+		case artifact.Create:
+			fmt.Printf("%s %s (%d bytes)\n", created, c.Path, c.NewSize)
+		case artifact.Update:
+			fmt.Printf("%s %s (%d -> %d bytes)\n", updated, c.Path, c.OldSize, c.NewSize)
+		default:
+			fmt.Printf("Unchanged %s\n", c.Path)
+		}
 	}
+}
 
-	// 3. Append new snapshot
-	snap := store.Snapshot{
-		TotalLOC:   result.TotalCode,
-		TotalFiles: result.TotalFiles,
-		CreatedAt:  time.Now().UTC(),
+// countSource counts a directory, the tree of a git revision of the repository
+// at path when rev is set, or an archive when path names one.
+func countSource(ctx context.Context, path, rev string, opts counter.Options) (*counter.LOCResult, error) {
+	if rev != "" {
+		return counter.CountTreeContext(ctx, path, rev, opts)
 	}
-	for _, lang := range result.Languages {
-		snap.Languages = append(snap.Languages, store.LanguageRecord{
-			Language: lang.Language,
-			Lines:    lang.Lines,
-			Code:     lang.Code,
-			Comments: lang.Comments,
-			Blanks:   lang.Blanks,
-			Files:    lang.Files,
-		})
+	if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+		return counter.CountArchiveContext(ctx, path, opts)
 	}
-	history = append(history, snap)
+	return counter.CountContext(ctx, path, opts)
+}
 
-	// 4. Ensure output directory exists
-	if err := os.MkdirAll(*output, 0755); err != nil {
-		log.Fatalf("mkdir: %v", err)
+// excludeOutput leaves the output directory out of the count when it lies
+// inside the counted directory, as .ghloc does by default. Otherwise the
+// history and charts written by one run would be counted by the next.
+func excludeOutput(opts *counter.Options, dir, output string) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return
+	}
+	absOutput, err := filepath.Abs(output)
+	if err != nil {
+		return
+	}
+	rel, err := filepath.Rel(absDir, absOutput)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return
 	}
+	opts.ExcludeDirs = append(opts.ExcludeDirs, filepath.ToSlash(rel))
+}
 
-	// 5. Write badge SVG
-	badgeSVG := locbadge.RenderSVG(locbadge.FormatLOC(result.TotalCode), badge.ColorBlue)
-	badgePath := filepath.Join(*output, "badge.svg")
-	if err := os.WriteFile(badgePath, badgeSVG, 0644); err != nil {
-		log.Fatalf("write badge: %v", err)
+// explainSource explains how the file at name is counted in a directory, or
+// in an archive when path names one.
+func explainSource(path, name string, opts counter.Options) (*counter.Explanation, error) {
+	if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+		return counter.ExplainArchive(path, name, opts)
 	}
-	fmt.Printf("Wrote %s\n", badgePath)
+	return counter.Explain(path, name, opts)
+}
 
-	// 6. Write chart SVG
-	chartSVG := chart.RenderHistoryChart(history)
-	chartPath := filepath.Join(*output, "chart.svg")
-	if err := os.WriteFile(chartPath, chartSVG, 0644); err != nil {
-		log.Fatalf("write chart: %v", err)
+// printSkipped summarizes the files that were not counted. The counter's
+// logger has already warned about files that could not be read.
+func printSkipped(result *counter.LOCResult) {
+	if len(result.Skipped) == 0 {
+		return
+	}
+	counts := result.SkippedByReason()
+	var parts []string
+	for _, reason := range []counter.SkipReason{
+		counter.SkipBinary, counter.SkipTooLarge, counter.SkipMinified,
+		counter.SkipUnreadable, counter.SkipUnknownLanguage,
+	} {
+		if counts[reason] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[reason], reason))
+		}
 	}
-	fmt.Printf("Wrote %s\n", chartPath)
+	fmt.Printf("Skipped %d files: %s\n", len(result.Skipped), strings.Join(parts, ", "))
+}
 
-	// 7. Save updated history
-	if err := store.SaveHistory(historyPath, history); err != nil {
-		log.Fatalf("save history: %v", err)
+// printExplanation writes a human-readable account of how a file is counted.
+func printExplanation(w io.Writer, e *counter.Explanation) {
+	fmt.Fprintf(w, "%s\n", e.Path)
+	if len(e.Candidates) > 0 {
+		fmt.Fprintf(w, "  candidates: %s\n", strings.Join(e.Candidates, ", "))
+	}
+	if e.Detected != "" {
+		fmt.Fprintf(w, "  detected:   %s (by %s)\n", e.Detected, e.Source)
+	}
+	if e.Submodule != "" {
+		fmt.Fprintf(w, "  submodule:  %s\n", e.Submodule)
+	}
+	switch {
+	case e.Excluded != "":
+		fmt.Fprintf(w, "  not counted: %s\n", e.Excluded)
+	case e.Skipped == counter.SkipUnreadable:
+		fmt.Fprintf(w, "  skipped:    %s (%v)\n", e.Skipped, e.Err)
+	case e.Skipped != "":
+		fmt.Fprintf(w, "  skipped:    %s\n", e.Skipped)
+	default:
+		fmt.Fprintf(w, "  language:   %s (%s)\n", e.Language, e.Category)
+		fmt.Fprintf(w, "  lines:      %d code, %d comments, %d blanks, complexity %d\n",
+			e.Stats.Code, e.Stats.Comments, e.Stats.Blanks, e.Stats.Complexity)
+		if e.Stats.Test {
+			fmt.Fprintln(w, "  test file:  yes")
+		}
+		if !e.InTotals {
+			fmt.Fprintln(w, "  not included in totals (code-only)")
+		}
+	}
+}
+
+// splitList splits a comma-separated flag value, dropping blank entries.
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// loadConfig reads the config file at path, or config.json in the output
+// directory when path is empty.
+func loadConfig(path, output string) *config.Config {
+	if path == "" {
+		path = filepath.Join(output, "config.json")
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		fatal("load config", "err", err)
 	}
-	fmt.Printf("Wrote %s (%d snapshots)\n", historyPath, len(history))
+	return cfg
 }
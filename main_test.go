@@ -0,0 +1,130 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMain runs the command itself when a test re-executes the test binary
+// with GHLOC_TEST_MAIN set, so tests can drive it end to end.
+func TestMain(m *testing.M) {
+	if os.Getenv("GHLOC_TEST_MAIN") != "" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runGhloc runs ghloc with args in dir and returns the "changed" step output.
+func runGhloc(t *testing.T, dir string, env []string, args ...string) string {
+	t.Helper()
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stepOutput := filepath.Join(t.TempDir(), "github_output")
+	cmd := exec.Command(exe, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), append(env, "GHLOC_TEST_MAIN=1", "GITHUB_OUTPUT="+stepOutput)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("ghloc %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	data, err := os.ReadFile(stepOutput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// writeTree creates a small repository in dir.
+func writeTree(t *testing.T, dir string) {
+	t.Helper()
+	files := map[string]string{
+		"main.go":      "package main\n\nfunc main() {}\n",
+		"util_test.go": "package main\n\n// TestUtil checks nothing.\nfunc TestUtil() {}\n",
+		"README.md":    "# Demo\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// readArtifacts returns the contents of every file under dir, by path.
+func readArtifacts(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		rel, _ := filepath.Rel(dir, path)
+		files[filepath.ToSlash(rel)] = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+// diffArtifacts reports files that differ between two artifact sets.
+func diffArtifacts(t *testing.T, got, want map[string]string) {
+	t.Helper()
+	for name, data := range want {
+		if got[name] != data {
+			t.Errorf("%s differs:\ngot:\n%s\nwant:\n%s", name, got[name], data)
+		}
+	}
+	for name := range got {
+		if _, ok := want[name]; !ok {
+			t.Errorf("unexpected artifact %s", name)
+		}
+	}
+}
+
+func TestRun_UnchangedTreeChangesNothing(t *testing.T) {
+	repo := t.TempDir()
+	writeTree(t, repo)
+
+	// The default output directory, .ghloc, lies inside the counted tree
+	if changed := runGhloc(t, repo, nil); changed != "changed=true" {
+		t.Errorf("first run: step output %q, want changed=true", changed)
+	}
+	first := readArtifacts(t, filepath.Join(repo, ".ghloc"))
+
+	if changed := runGhloc(t, repo, nil); changed != "changed=false" {
+		t.Errorf("second run: step output %q, want changed=false", changed)
+	}
+	diffArtifacts(t, readArtifacts(t, filepath.Join(repo, ".ghloc")), first)
+}
+
+func TestRun_Reproducible(t *testing.T) {
+	// Two checkouts of the same tree, with the same history, counted at the
+	// same SOURCE_DATE_EPOCH, get byte-identical artifacts
+	var outputs [2]map[string]string
+	for i := range outputs {
+		repo := t.TempDir()
+		writeTree(t, repo)
+		runGhloc(t, repo, []string{"SOURCE_DATE_EPOCH=1700000000"})
+
+		os.WriteFile(filepath.Join(repo, "lib.go"), []byte("package main\n\nfunc lib() int {\n\treturn 1\n}\n"), 0644)
+		runGhloc(t, repo, []string{"SOURCE_DATE_EPOCH=1700086400"}, "-files")
+		outputs[i] = readArtifacts(t, filepath.Join(repo, ".ghloc"))
+
+		// Counting the same tree again, later, leaves every artifact as it was
+		runGhloc(t, repo, []string{"SOURCE_DATE_EPOCH=1700172800"}, "-files")
+		diffArtifacts(t, readArtifacts(t, filepath.Join(repo, ".ghloc")), outputs[i])
+	}
+
+	if !strings.Contains(outputs[0]["history.json"], `"created_at": "2023-11-15T22:13:20Z"`) {
+		t.Errorf("history.json should record SOURCE_DATE_EPOCH:\n%s", outputs[0]["history.json"])
+	}
+	diffArtifacts(t, outputs[1], outputs[0])
+}
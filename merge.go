@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/rjwalters/ghloc/internal/store"
+)
+
+// mergeDriverName is the merge driver key used in git config and gitattributes.
+const mergeDriverName = "ghloc-history"
+
+// runMergeDriver implements a git merge driver for history.json. Git invokes it
+// as `ghloc merge-driver %O %A %B` and expects the merged result in %A.
+func runMergeDriver(args []string) {
+	fs := flag.NewFlagSet("merge-driver", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: ghloc merge-driver <ancestor> <ours> <theirs>")
+	}
+	fs.Parse(args)
+	if fs.NArg() != 3 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	ancestorPath, oursPath, theirsPath := fs.Arg(0), fs.Arg(1), fs.Arg(2)
+
+	ancestor, err := store.LoadHistory(ancestorPath)
+	if err != nil {
+		fatal("load ancestor", "err", err)
+	}
+	ours, err := store.LoadHistory(oursPath)
+	if err != nil {
+		fatal("load ours", "err", err)
+	}
+	theirs, err := store.LoadHistory(theirsPath)
+	if err != nil {
+		fatal("load theirs", "err", err)
+	}
+
+	merged := store.MergeHistory(ancestor, theirs, ours)
+	if err := store.SaveHistory(oursPath, merged); err != nil {
+		fatal("save merged history", "err", err)
+	}
+}
+
+// runInstallMergeDriver registers the history merge driver in the local git
+// config and maps the history file to it via .git/info/attributes, so nothing
+// needs to be committed to the repository.
+func runInstallMergeDriver(args []string) {
+	fs := flag.NewFlagSet("install-merge-driver", flag.ExitOnError)
+	binary := fs.String("binary", "", "path to the ghloc binary git should invoke (default: this executable)")
+	pattern := fs.String("path", ".ghloc/history.json", "gitattributes pattern for the history file")
+	fs.Parse(args)
+
+	if *binary == "" {
+		exe, err := os.Executable()
+		if err != nil {
+			fatal("locate executable", "err", err)
+		}
+		*binary = exe
+	}
+
+	driver := shellQuote(*binary) + " merge-driver %O %A %B"
+	if _, err := git("config", "merge."+mergeDriverName+".name", "ghloc history.json union merge"); err != nil {
+		fatal("git config", "err", err)
+	}
+	if _, err := git("config", "merge."+mergeDriverName+".driver", driver); err != nil {
+		fatal("git config", "err", err)
+	}
+
+	attrPath, err := git("rev-parse", "--git-path", "info/attributes")
+	if err != nil {
+		fatal("locate attributes file", "err", err)
+	}
+	line := *pattern + " merge=" + mergeDriverName
+	added, err := appendLineIfMissing(attrPath, line)
+	if err != nil {
+		fatal("write attributes", "err", err)
+	}
+
+	fmt.Printf("Configured merge driver %q: %s\n", mergeDriverName, driver)
+	if added {
+		fmt.Printf("Added %q to %s\n", line, attrPath)
+	}
+}
+
+// git runs a git command in the current directory and returns its trimmed stdout.
+func git(args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// appendLineIfMissing appends line to the file at path unless it is already present.
+func appendLineIfMissing(path, line string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	for _, l := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(l) == line {
+			return false, nil
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return false, err
+	}
+	if len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+		data = append(data, '\n')
+	}
+	data = append(data, line+"\n"...)
+	return true, os.WriteFile(path, data, 0644)
+}
+
+// shellQuote quotes s for use in a git config command line, which git runs via sh.
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r == '/' || r == '.' || r == '-' || r == '_' ||
+			(r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'))
+	}) == -1 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rjwalters/ghloc/internal/report"
+	"github.com/rjwalters/ghloc/internal/snapshot"
+	"github.com/rjwalters/ghloc/internal/store"
+)
+
+// runTop prints the largest files in dir and the files that grew most since
+// the baseline snapshot chosen by report.Baseline: the newest one with
+// per-file records whose counts differ from the live count.
+func runTop(args []string) {
+	fs := flag.NewFlagSet("top", flag.ExitOnError)
+	dir := fs.String("dir", ".", "directory or archive to count")
+	output := fs.String("output", ".ghloc", "output directory containing history.json")
+	configPath := fs.String("config", "", "path to config file (default: <output>/config.json)")
+	n := fs.Int("files", 10, "number of files to list")
+	fs.Parse(args)
+	if *n < 1 {
+		fatal("-files must be at least 1", "files", *n)
+	}
+
+	opts := loadConfig(*configPath, *output).CounterOptions()
+	excludeOutput(&opts, *dir, *output)
+	result, err := countSource(context.Background(), *dir, "", opts)
+	if err != nil {
+		fatal("count", "err", err)
+	}
+	current := snapshot.FromResult(result, time.Now().UTC())
+
+	history, err := store.LoadHistory(filepath.Join(*output, "history.json"))
+	if err != nil {
+		fatal("load history", "err", err)
+	}
+	previous := report.Baseline(history, current)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Printf("Largest files (top %d by code lines)\n", *n)
+	fmt.Fprintln(w, "Code\tLines\tBytes\tComplexity\tLanguage\tPath")
+	for _, f := range report.LargestFiles(current.Files, *n) {
+		fmt.Fprintf(w, "%d\t%d\t%d\t%d\t%s\t%s\n", f.Code, f.Lines, f.Bytes, f.Complexity, f.Language, f.Path)
+	}
+	w.Flush()
+
+	fmt.Println()
+	if previous == nil {
+		fmt.Println("No earlier snapshot with per-file records and different counts; run ghloc with -files to enable growth reports.")
+		return
+	}
+
+	fmt.Printf("Fastest growing files since %s\n", previous.CreatedAt.Format(time.DateOnly))
+	fmt.Fprintln(w, "Delta\tBefore\tAfter\tLanguage\tPath")
+	for _, g := range report.GrowingFiles(previous.Files, current.Files, *n) {
+		fmt.Fprintf(w, "+%d\t%d\t%d\t%s\t%s\n", g.Delta, g.Before, g.After, g.Language, g.Path)
+	}
+	w.Flush()
+}